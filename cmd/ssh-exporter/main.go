@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
@@ -13,6 +14,7 @@ import (
 	"github.com/tastythames/ssh-exporter/internal/inventory"
 	"github.com/tastythames/ssh-exporter/internal/metrics"
 	"github.com/tastythames/ssh-exporter/internal/scheduler"
+	"github.com/tastythames/ssh-exporter/internal/sshclient"
 )
 
 func getenv(k, fb string) string {
@@ -22,6 +24,36 @@ func getenv(k, fb string) string {
 	return fb
 }
 
+func getenvInt(k string, fb int) int {
+	if v := os.Getenv(k); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+	}
+	return fb
+}
+
+func jobAuth(a inventory.SSHAuth) scheduler.Auth {
+	return scheduler.Auth{
+		Mode:         a.Mode,
+		PasswordEnv:  a.PasswordEnv,
+		PasswordFile: a.PasswordFile,
+
+		KeyPath:  a.KeyPath,
+		CertPath: a.CertPath,
+
+		KeyPassphraseEnv:  a.KeyPassphraseEnv,
+		KeyPassphraseFile: a.KeyPassphraseFile,
+
+		AgentSocket: a.AgentSocket,
+
+		TOTPSeedEnv:    a.TOTPSeedEnv,
+		TOTPSeedFile:   a.TOTPSeedFile,
+		PasswordPrompt: a.PasswordPrompt,
+		TOTPPrompt:     a.TOTPPrompt,
+	}
+}
+
 func main() {
 	listen := getenv("EXPORTER_LISTEN", ":9222")
 
@@ -36,42 +68,94 @@ func main() {
 
 	jobs := make([]scheduler.Job, 0, len(inv.Targets))
 	for _, t := range inv.Targets {
-		jobs = append(jobs, scheduler.Job{
+		job := scheduler.Job{
 			Target: t.Address,
+			Host:   t.Host,
+			Port:   t.Port,
 			Labels: t.Labels,
+			Mode:   t.Mode,
+
+			Collectors: t.Collectors,
 
 			SSHUser: t.SSH.User,
+			Auth:    jobAuth(t.SSH.Auth),
 
-			AuthMode:     t.SSH.Auth.Mode,
-			PasswordEnv:  t.SSH.Auth.PasswordEnv,
-			PasswordFile: t.SSH.Auth.PasswordFile,
+			KnownHostsFiles: t.SSH.KnownHostsFiles,
+			Algorithms:      t.SSH.Algorithms,
+		}
+		for _, j := range t.SSH.Jump {
+			job.Jump = append(job.Jump, scheduler.Hop{
+				Host: j.Host,
+				Port: j.Port,
+				User: j.User,
+				Auth: jobAuth(j.Auth),
+
+				KnownHostsFiles: j.KnownHostsFiles,
+				Algorithms:      j.Algorithms,
+			})
+		}
+		jobs = append(jobs, job)
+	}
 
-			KeyPath: t.SSH.Auth.KeyPath,
-		})
+	// 2) ssh client (shared by workers)
+	sshCfg := sshclient.LoadConfig()
+	if sshCfg.InsecureSkipHostKey {
+		log.Printf("WARNING: host key verification disabled (SSH_INSECURE_SKIP_HOSTKEY)")
+	} else {
+		log.Printf("config: known_hosts=%v", sshCfg.KnownHostsFiles)
+	}
+	cli, err := sshclient.New(sshCfg)
+	if err != nil {
+		log.Fatalf("ssh client: %v", err)
 	}
 
-	// 2) cache + scheduler
+	// 3) cache + scheduler
 	c := cache.NewMemCache()
 
+	interval := 10 * time.Second
+	breaker := scheduler.NewBreaker(scheduler.BreakerOptions{
+		Threshold:   getenvInt("SCRAPE_BACKOFF_THRESHOLD", 3),
+		BaseBackoff: interval,
+		MaxBackoff:  time.Duration(getenvInt("SCRAPE_BACKOFF_MAX_SECONDS", 600)) * time.Second,
+	})
+
+	limiter := scheduler.NewLimiter(scheduler.LimitOptions{
+		PerBastion: getenvInt("LIMIT_PER_BASTION", 0),
+		Label:      os.Getenv("LIMIT_LABEL"),
+		PerLabel:   getenvInt("LIMIT_PER_LABEL", 0),
+		SubnetBits: getenvInt("LIMIT_SUBNET_BITS", 24),
+		PerSubnet:  getenvInt("LIMIT_PER_SUBNET", 0),
+
+		Wait:        interval,
+		DefaultPort: sshCfg.Port,
+	})
+
 	jobCh := make(chan scheduler.Job, 100) // buffer สำคัญมาก
 	sched := scheduler.NewScheduler(scheduler.Options{
-		Interval: 10 * time.Second,
+		Interval: interval,
 		Jitter:   2 * time.Second,
 		JobCh:    jobCh,
+		Breaker:  breaker,
 	})
 
 	// worker pool size
 	workers := 5
 	for i := 0; i < workers; i++ {
-		go scheduler.StartWorker(i, jobCh, c)
+		go scheduler.StartWorker(i, jobCh, scheduler.WorkerOptions{
+			Cache:   c,
+			Client:  cli,
+			Breaker: breaker,
+			Limiter: limiter,
+		})
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	go sched.Run(ctx, jobs)
 
-	// 3) HTTP
+	// 4) HTTP
 	r := metrics.NewRenderer(c)
+	r.Pool = cli
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/health", func(w http.ResponseWriter, _ *http.Request) {
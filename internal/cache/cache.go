@@ -6,10 +6,83 @@ import (
 )
 
 type Result struct {
-	At     time.Time
+	At       time.Time // scrape start
+	Duration time.Duration
+	Labels   map[string]string
+	Err      error
+
+	// Families are the collector metrics, one entry per metric name.
+	Families []Family
+
+	// UserCertExpiry is the expiry of the user certificate used; zero if none.
+	UserCertExpiry time.Time
+
+	// ErrReason classifies Err (see metrics.ErrorReasons); "" when Err is nil.
+	ErrReason string
+
+	// HostKey is nil until the target presented a host key.
+	HostKey *HostKey
+
+	// Crypto is nil until a handshake with the target completed.
+	Crypto *Crypto
+
+	// ExitCodes is the remote exit status per collector (-1: none sent).
+	ExitCodes map[string]int
+
+	// circuit breaker state after this result; Backoff is 0 unless the
+	// target is being skipped
+	ConsecutiveFailures int
+	Backoff             time.Duration
+
+	// Phases is seconds spent per SSH phase (connect, kex, auth, session, command).
+	Phases map[string]float64
+}
+
+// MetricType is the Prometheus type of a family.
+type MetricType string
+
+const (
+	Gauge   MetricType = "gauge"
+	Counter MetricType = "counter"
+)
+
+// Family is one metric name with its metadata and samples.
+type Family struct {
+	Name    string
+	Help    string
+	Type    MetricType
+	Samples []Sample
+}
+
+// Sample is one series of a family; Labels are added to the target's labels.
+type Sample struct {
 	Labels map[string]string
-	Values map[string]float64
-	Err    error
+	Value  float64
+}
+
+// Add appends a sample to the family called name, creating it on first use.
+func (r *Result) Add(name, help string, typ MetricType, labels map[string]string, v float64) {
+	for i := range r.Families {
+		if r.Families[i].Name == name {
+			r.Families[i].Samples = append(r.Families[i].Samples, Sample{Labels: labels, Value: v})
+			return
+		}
+	}
+	r.Families = append(r.Families, Family{
+		Name:    name,
+		Help:    help,
+		Type:    typ,
+		Samples: []Sample{{Labels: labels, Value: v}},
+	})
+}
+
+// HostKey is the host key seen on the last connection to a target.
+type HostKey struct {
+	Type        string
+	Fingerprint string
+	Changed     bool // differs from the trusted (known_hosts / TOFU) key
+
+	CertValidBefore time.Time // host certificate expiry; zero if none
 }
 
 // Cache is the interface used by scheduler/metrics.
@@ -46,3 +119,16 @@ func (c *MemCache) Snapshot() map[string]Result {
 	}
 	return out
 }
+
+// Crypto is the algorithm set negotiated with a target.
+type Crypto struct {
+	KeyExchange string
+	HostKey     string
+
+	CipherClientServer string
+	CipherServerClient string
+	MACClientServer    string
+	MACServerClient    string
+
+	Weak bool // any of the above is considered insecure
+}
@@ -0,0 +1,116 @@
+package collector
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/tastythames/ssh-exporter/internal/cache"
+	"github.com/tastythames/ssh-exporter/internal/sshclient"
+)
+
+// Type is the Prometheus metric type of a sample.
+type Type = cache.MetricType
+
+const (
+	Gauge   = cache.Gauge
+	Counter = cache.Counter
+)
+
+// Sample is one value produced by a collector. Samples sharing a Name form
+// one family and must agree on Help and Type; Labels tell them apart
+// (e.g. {"device": "eth0"}) and are added to the target's labels.
+type Sample struct {
+	Name   string
+	Help   string
+	Type   Type
+	Labels map[string]string
+	Value  float64
+}
+
+// Collector turns the stdout of one allowed command into samples.
+// Collect must not keep out; it is called once per scrape and target.
+type Collector interface {
+	Name() string
+	Command() sshclient.AllowedCommand
+	Collect(out string) ([]Sample, error)
+}
+
+// Configurable collectors take per-target settings from the inventory
+// (collector_config.<name>); Configure returns a collector bound to them.
+type Configurable interface {
+	Configure(cfg map[string]string) (Collector, error)
+}
+
+// Default is used for targets without a collectors: list.
+var Default = []string{"uptime"}
+
+var (
+	mu         sync.RWMutex
+	collectors = map[string]Collector{}
+)
+
+// Register adds c to the registry; collectors call it from init.
+// Registering a name twice panics (programming error).
+func Register(c Collector) {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := collectors[c.Name()]; ok {
+		panic(fmt.Sprintf("collector %q registered twice", c.Name()))
+	}
+	collectors[c.Name()] = c
+}
+
+// Get returns the collector registered as name.
+func Get(name string) (Collector, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	c, ok := collectors[name]
+	return c, ok
+}
+
+// Names lists registered collectors, sorted.
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	out := make([]string, 0, len(collectors))
+	for n := range collectors {
+		out = append(out, n)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Lookup resolves names (Default when empty) to collectors, configured from
+// cfg (collector name -> settings).
+func Lookup(names []string, cfg map[string]map[string]string) ([]Collector, error) {
+	if len(names) == 0 {
+		names = Default
+	}
+	for n := range cfg {
+		c, ok := Get(n)
+		if !ok {
+			return nil, fmt.Errorf("config for unknown collector %q", n)
+		}
+		if _, ok := c.(Configurable); !ok {
+			return nil, fmt.Errorf("collector %q takes no config", n)
+		}
+	}
+
+	out := make([]Collector, 0, len(names))
+	for _, n := range names {
+		c, ok := Get(n)
+		if !ok {
+			return nil, fmt.Errorf("unknown collector %q (have %v)", n, Names())
+		}
+		if cc, ok := c.(Configurable); ok && cfg[n] != nil {
+			configured, err := cc.Configure(cfg[n])
+			if err != nil {
+				return nil, fmt.Errorf("collector %q: %w", n, err)
+			}
+			c = configured
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
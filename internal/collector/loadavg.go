@@ -0,0 +1,33 @@
+package collector
+
+import "github.com/tastythames/ssh-exporter/internal/sshclient"
+
+func init() { Register(loadavg{}) }
+
+// loadavg reads /proc/loadavg: load averages, scheduling entities, last PID.
+type loadavg struct{}
+
+func (loadavg) Name() string                      { return "loadavg" }
+func (loadavg) Command() sshclient.AllowedCommand { return sshclient.CmdLoadavg() }
+
+func (loadavg) Collect(out string) ([]Sample, error) {
+	la, err := sshclient.ParseLoadavg(out)
+	if err != nil {
+		return nil, err
+	}
+	samples := []Sample{
+		{Name: "ssh_load1", Help: "1m load average.", Type: Gauge, Value: la.Load1},
+		{Name: "ssh_load5", Help: "5m load average.", Type: Gauge, Value: la.Load5},
+		{Name: "ssh_load15", Help: "15m load average.", Type: Gauge, Value: la.Load15},
+	}
+	if la.HasEntities {
+		samples = append(samples,
+			Sample{Name: "ssh_sched_entities_running", Help: "Runnable scheduling entities (processes/threads).", Type: Gauge, Value: float64(la.Running)},
+			Sample{Name: "ssh_sched_entities", Help: "Existing scheduling entities (processes/threads).", Type: Gauge, Value: float64(la.Total)},
+		)
+	}
+	if la.HasLastPID {
+		samples = append(samples, Sample{Name: "ssh_last_pid", Help: "PID most recently assigned by the kernel.", Type: Gauge, Value: float64(la.LastPID)})
+	}
+	return samples, nil
+}
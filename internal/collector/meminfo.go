@@ -0,0 +1,29 @@
+package collector
+
+import "github.com/tastythames/ssh-exporter/internal/sshclient"
+
+func init() { Register(meminfo{}) }
+
+// meminfo exports every /proc/meminfo field, named like node_exporter:
+// ssh_memory_<field>_bytes, or ssh_memory_<field> for counts (HugePages_*).
+type meminfo struct{}
+
+func (meminfo) Name() string                      { return "meminfo" }
+func (meminfo) Command() sshclient.AllowedCommand { return sshclient.CmdMeminfo() }
+
+func (meminfo) Collect(out string) ([]Sample, error) {
+	fields, err := sshclient.ParseMeminfoFields(out)
+	if err != nil {
+		return nil, err
+	}
+	samples := make([]Sample, 0, len(fields))
+	for _, f := range fields {
+		name := "ssh_memory_" + f.Name
+		help := "Memory information field " + f.Name + "."
+		if f.Bytes {
+			name += "_bytes"
+		}
+		samples = append(samples, Sample{Name: name, Help: help, Type: Gauge, Value: f.Value})
+	}
+	return samples, nil
+}
@@ -0,0 +1,82 @@
+package collector
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/tastythames/ssh-exporter/internal/sshclient"
+)
+
+func init() { Register(netdev{}) }
+
+// netdev exports /proc/net/dev columns as counters per device, named like
+// node_exporter: ssh_network_{receive,transmit}_<column>_total{device=...}.
+// Settings: include / exclude (regexps on the device name).
+type netdev struct {
+	include *regexp.Regexp // nil = all
+	exclude *regexp.Regexp // nil = none
+}
+
+func (netdev) Name() string                      { return "netdev" }
+func (netdev) Command() sshclient.AllowedCommand { return sshclient.CmdNetDev() }
+
+func (netdev) Configure(cfg map[string]string) (Collector, error) {
+	var nd netdev
+	keys := make([]string, 0, len(cfg))
+	for k := range cfg {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		re, err := regexp.Compile(cfg[k])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", k, err)
+		}
+		switch k {
+		case "include":
+			nd.include = re
+		case "exclude":
+			nd.exclude = re
+		default:
+			return nil, fmt.Errorf("unknown setting %q (want include, exclude)", k)
+		}
+	}
+	return nd, nil
+}
+
+func (nd netdev) Collect(out string) ([]Sample, error) {
+	devs, err := sshclient.ParseNetDev(out)
+	if err != nil {
+		return nil, err
+	}
+	var samples []Sample
+	for _, d := range devs {
+		if nd.include != nil && !nd.include.MatchString(d.Device) {
+			continue
+		}
+		if nd.exclude != nil && nd.exclude.MatchString(d.Device) {
+			continue
+		}
+		labels := map[string]string{"device": d.Device}
+		for _, c := range d.Receive {
+			samples = append(samples, Sample{
+				Name:   "ssh_network_receive_" + c.Name + "_total",
+				Help:   "Network device statistic receive_" + c.Name + ".",
+				Type:   Counter,
+				Labels: labels,
+				Value:  c.Value,
+			})
+		}
+		for _, c := range d.Transmit {
+			samples = append(samples, Sample{
+				Name:   "ssh_network_transmit_" + c.Name + "_total",
+				Help:   "Network device statistic transmit_" + c.Name + ".",
+				Type:   Counter,
+				Labels: labels,
+				Value:  c.Value,
+			})
+		}
+	}
+	return samples, nil
+}
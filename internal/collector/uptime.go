@@ -0,0 +1,24 @@
+package collector
+
+import "github.com/tastythames/ssh-exporter/internal/sshclient"
+
+func init() { Register(uptime{}) }
+
+// uptime reads /proc/uptime.
+type uptime struct{}
+
+func (uptime) Name() string                      { return "uptime" }
+func (uptime) Command() sshclient.AllowedCommand { return sshclient.CmdUptime() }
+
+func (uptime) Collect(out string) ([]Sample, error) {
+	secs, err := sshclient.ParseUptimeSeconds(out)
+	if err != nil {
+		return nil, err
+	}
+	return []Sample{{
+		Name:  "ssh_os_uptime_seconds",
+		Help:  "Seconds since the target booted (/proc/uptime).",
+		Type:  Gauge,
+		Value: secs,
+	}}, nil
+}
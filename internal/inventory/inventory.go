@@ -2,10 +2,16 @@ package inventory
 
 import (
 	"fmt"
+	"net"
 	"os"
+	"regexp"
+	"strconv"
 	"strings"
 
 	"gopkg.in/yaml.v3"
+
+	"github.com/tastythames/ssh-exporter/internal/collector"
+	"github.com/tastythames/ssh-exporter/internal/sshclient"
 )
 
 type Inventory struct {
@@ -14,26 +20,70 @@ type Inventory struct {
 
 type Target struct {
 	Name    string
-	Address string
-	Mode    string
+	Address string // as written in the inventory (used as the target label)
+	Host    string // host to dial: Address without port/brackets, or ssh_config HostName
+	Port    int    // 0 = SSH_PORT
+	Mode    string // "ssh" (shell commands) | "sftp" (SFTP subsystem only)
 	Labels  map[string]string
 
+	// resolved and configured (collector_config) once at load;
+	// collector.Default when the inventory lists none
+	Collectors []collector.Collector
+
 	SSH SSHConfig
 }
 
 type SSHConfig struct {
 	User string
 	Auth SSHAuth
+
+	// bastions in connect order (Jump[0] is dialed directly)
+	Jump []JumpHost
+
+	// from ssh_config UserKnownHostsFile; empty = global policy
+	KnownHostsFiles []string
+
+	// crypto allowlists; non-empty lists override the SSH_* env defaults
+	Algorithms sshclient.Algorithms
+}
+
+type JumpHost struct {
+	Host string
+	Port int // 0 = SSH_PORT
+	User string
+	Auth SSHAuth
+
+	KnownHostsFiles []string
+	Algorithms      sshclient.Algorithms
 }
 
 type SSHAuth struct {
-	Mode         string // "password_env" | "password_file"
+	Mode         string // "password_env" | "password_file" | "key" | "cert" | "agent"
 	PasswordEnv  string // e.g. SSH_PASS_ECS1
 	PasswordFile string // e.g. /run/secrets/ecs-1.pass
-	KeyPath      string // future
+	KeyPath      string // e.g. /run/secrets/ecs-1.key (mode=key|cert)
+	CertPath     string // e.g. /run/secrets/ecs-1.key-cert.pub (mode=cert)
+
+	// optional, for encrypted keys
+	KeyPassphraseEnv  string // e.g. SSH_KEY_PASS_ECS1
+	KeyPassphraseFile string // e.g. /run/secrets/ecs-1.key.pass
+
+	AgentSocket string // optional override of SSH_AUTH_SOCK (mode=agent)
+
+	// optional TOTP second factor (keyboard-interactive), base32 seed
+	TOTPSeedEnv  string // e.g. SSH_TOTP_BASTION
+	TOTPSeedFile string // e.g. /run/secrets/bastion.totp
+
+	// optional prompt rules (regexps), see sshclient.DefaultPasswordPrompt
+	PasswordPrompt string
+	TOTPPrompt     string
 }
 
 type rawInventory struct {
+	// optional OpenSSH client config (~/.ssh/config style) consulted for every
+	// target and jump host; inventory values win over it
+	SSHConfig string `yaml:"ssh_config"`
+
 	Targets []rawTarget `yaml:"targets"`
 }
 
@@ -43,12 +93,32 @@ type rawTarget struct {
 	Mode    string            `yaml:"mode"`
 	Labels  map[string]string `yaml:"labels"`
 
+	Collectors      []string                     `yaml:"collectors"`
+	CollectorConfig map[string]map[string]string `yaml:"collector_config"`
+
 	SSH rawSSH `yaml:"ssh"`
 }
 
 type rawSSH struct {
-	User string  `yaml:"user"`
-	Auth rawAuth `yaml:"auth"`
+	User       string        `yaml:"user"`
+	Port       int           `yaml:"port"`
+	Auth       rawAuth       `yaml:"auth"`
+	Jump       []rawJump     `yaml:"jump"`
+	Algorithms rawAlgorithms `yaml:"algorithms"`
+}
+
+type rawJump struct {
+	Address    string        `yaml:"address"`
+	User       string        `yaml:"user"`
+	Auth       rawAuth       `yaml:"auth"`
+	Algorithms rawAlgorithms `yaml:"algorithms"`
+}
+
+type rawAlgorithms struct {
+	KeyExchanges []string `yaml:"kex"`
+	Ciphers      []string `yaml:"ciphers"`
+	MACs         []string `yaml:"macs"`
+	HostKeys     []string `yaml:"host_keys"`
 }
 
 type rawAuth struct {
@@ -56,6 +126,17 @@ type rawAuth struct {
 	PasswordEnv  string `yaml:"password_env"`
 	PasswordFile string `yaml:"password_file"`
 	KeyPath      string `yaml:"key_path"`
+	CertPath     string `yaml:"cert_path"`
+
+	KeyPassphraseEnv  string `yaml:"key_passphrase_env"`
+	KeyPassphraseFile string `yaml:"key_passphrase_file"`
+
+	AgentSocket string `yaml:"agent_socket"`
+
+	TOTPSeedEnv    string `yaml:"totp_seed_env"`
+	TOTPSeedFile   string `yaml:"totp_seed_file"`
+	PasswordPrompt string `yaml:"password_prompt"`
+	TOTPPrompt     string `yaml:"totp_prompt"`
 }
 
 func Load(path string) (*Inventory, error) {
@@ -69,7 +150,13 @@ func Load(path string) (*Inventory, error) {
 		return nil, fmt.Errorf("parse yaml: %w", err)
 	}
 
+	sshCfg, err := loadSSHConfig(ri.SSHConfig)
+	if err != nil {
+		return nil, err
+	}
+
 	out := &Inventory{Targets: make([]Target, 0, len(ri.Targets))}
+	seen := make(map[string]string) // address -> target name
 	for _, t := range ri.Targets {
 		name := strings.TrimSpace(t.Name)
 		addr := strings.TrimSpace(t.Address)
@@ -80,6 +167,25 @@ func Load(path string) (*Inventory, error) {
 		if name == "" {
 			name = addr
 		}
+		// address is the target label and the key of results and backoff
+		if other, ok := seen[addr]; ok {
+			return nil, fmt.Errorf("target %q: address %q is already used by target %q (put the port in the address: host:port)", name, addr, other)
+		}
+		seen[addr] = name
+
+		host, port, err := splitAddress(addr)
+		if err != nil {
+			return nil, fmt.Errorf("target %q: address: %w", name, err)
+		}
+		if p := t.SSH.Port; p != 0 {
+			if p < 0 || p > 65535 {
+				return nil, fmt.Errorf("target %q: ssh.port %d out of range", name, p)
+			}
+			if port != 0 && port != p {
+				return nil, fmt.Errorf("target %q: address port %d conflicts with ssh.port %d", name, port, p)
+			}
+			port = p
+		}
 
 		mode := strings.TrimSpace(t.Mode)
 		if mode == "" {
@@ -92,61 +198,280 @@ func Load(path string) (*Inventory, error) {
 		}
 		labels["name"] = name
 
-		// Defaults for SSH
+		// ssh_config fills what the inventory leaves unset
 		user := strings.TrimSpace(t.SSH.User)
+		hc := resolveHost(sshCfg, host, user)
+		if hc.HostName != "" {
+			host = hc.HostName
+		}
+		if port == 0 {
+			port = hc.Port
+		}
 		if user == "" {
-			user = "root" // lab-friendly default
+			user = hc.User
 		}
 
-		authMode := strings.TrimSpace(t.SSH.Auth.Mode)
-		passEnv := strings.TrimSpace(t.SSH.Auth.PasswordEnv)
-		passFile := strings.TrimSpace(t.SSH.Auth.PasswordFile)
-		keyPath := strings.TrimSpace(t.SSH.Auth.KeyPath)
-
-		// ---- Smart default for auth mode ----
-		if authMode == "" {
-			if passFile != "" {
-				authMode = "password_file"
-			} else {
-				authMode = "password_env"
-			}
+		// Defaults for SSH
+		if user == "" {
+			user = "root" // lab-friendly default
 		}
 
 		// Validate
 		switch mode {
-		case "ssh":
-			switch authMode {
-			case "password_env":
-				if passEnv == "" {
-					return nil, fmt.Errorf("target %q: ssh.auth.password_env is required for password_env mode", name)
-				}
-			case "password_file":
-				if passFile == "" {
-					return nil, fmt.Errorf("target %q: ssh.auth.password_file is required for password_file mode", name)
-				}
-			default:
-				return nil, fmt.Errorf("target %q: unsupported ssh.auth.mode %q", name, authMode)
-			}
+		case "ssh", "sftp":
 		default:
 			return nil, fmt.Errorf("target %q: unsupported mode %q", name, mode)
 		}
 
+		cols, err := collector.Lookup(trimList(t.Collectors), t.CollectorConfig)
+		if err != nil {
+			return nil, fmt.Errorf("target %q: collectors: %w", name, err)
+		}
+		if mode == "sftp" {
+			for _, col := range cols {
+				if col.Command().Path() == "" {
+					return nil, fmt.Errorf("target %q: collector %q needs a shell (mode sftp)", name, col.Name())
+				}
+			}
+		}
+
+		auth, err := parseAuth("ssh.auth", identityAuth(t.SSH.Auth, hc))
+		if err != nil {
+			return nil, fmt.Errorf("target %q: %w", name, err)
+		}
+		algos, err := parseAlgorithms("ssh.algorithms", t.SSH.Algorithms)
+		if err != nil {
+			return nil, fmt.Errorf("target %q: %w", name, err)
+		}
+
+		jump := make([]JumpHost, 0, len(t.SSH.Jump))
+		for i, j := range t.SSH.Jump {
+			field := fmt.Sprintf("ssh.jump[%d]", i)
+
+			jaddr := strings.TrimSpace(j.Address)
+			if jaddr == "" {
+				return nil, fmt.Errorf("target %q: %s.address is empty", name, field)
+			}
+			jhost, jport, err := splitAddress(jaddr)
+			if err != nil {
+				return nil, fmt.Errorf("target %q: %s.address: %w", name, field, err)
+			}
+			juser := strings.TrimSpace(j.User)
+			jhc := resolveHost(sshCfg, jhost, juser)
+			if jhc.HostName != "" {
+				jhost = jhc.HostName
+			}
+			if jport == 0 {
+				jport = jhc.Port
+			}
+			if juser == "" {
+				juser = jhc.User
+			}
+			if juser == "" {
+				juser = user
+			}
+			jauth, err := parseAuth(field+".auth", identityAuth(j.Auth, jhc))
+			if err != nil {
+				return nil, fmt.Errorf("target %q: %w", name, err)
+			}
+			jalgos, err := parseAlgorithms(field+".algorithms", j.Algorithms)
+			if err != nil {
+				return nil, fmt.Errorf("target %q: %w", name, err)
+			}
+			jump = append(jump, JumpHost{
+				Host:            jhost,
+				Port:            jport,
+				User:            juser,
+				Auth:            jauth,
+				KnownHostsFiles: jhc.UserKnownHostsFiles,
+				Algorithms:      jalgos,
+			})
+		}
+		if len(jump) == 0 {
+			jump, err = proxyJumpHosts(sshCfg, hc.ProxyJump, user)
+			if err != nil {
+				return nil, fmt.Errorf("target %q: ssh_config: %w", name, err)
+			}
+		}
+
 		out.Targets = append(out.Targets, Target{
 			Name:    name,
 			Address: addr,
+			Host:    host,
+			Port:    port,
 			Mode:    mode,
 			Labels:  labels,
+
+			Collectors: cols,
 			SSH: SSHConfig{
 				User: user,
-				Auth: SSHAuth{
-					Mode:         authMode,
-					PasswordEnv:  passEnv,
-					PasswordFile: passFile,
-					KeyPath:      keyPath,
-				},
+				Auth: auth,
+				Jump: jump,
+
+				KnownHostsFiles: hc.UserKnownHostsFiles,
+				Algorithms:      algos,
 			},
 		})
 	}
 
 	return out, nil
 }
+
+// splitAddress accepts "host", "host:port", "[v6]", "[v6]:port" and a bare
+// IPv6 address. port is 0 when the address has none.
+func splitAddress(addr string) (host string, port int, err error) {
+	switch {
+	case strings.HasPrefix(addr, "["):
+		if strings.HasSuffix(addr, "]") {
+			host = addr[1 : len(addr)-1]
+			break
+		}
+		h, p, err := net.SplitHostPort(addr)
+		if err != nil {
+			return "", 0, err
+		}
+		host = h
+		if port, err = parsePort(p); err != nil {
+			return "", 0, err
+		}
+
+	case strings.Count(addr, ":") == 1:
+		h, p, err := net.SplitHostPort(addr)
+		if err != nil {
+			return "", 0, err
+		}
+		host = h
+		if port, err = parsePort(p); err != nil {
+			return "", 0, err
+		}
+
+	default:
+		// hostname, IPv4 or bare IPv6 (no port)
+		host = addr
+	}
+
+	if host == "" {
+		return "", 0, fmt.Errorf("empty host in %q", addr)
+	}
+	if strings.Contains(host, ":") {
+		ip, _, _ := strings.Cut(host, "%") // allow zone, e.g. fe80::1%eth0
+		if net.ParseIP(ip) == nil {
+			return "", 0, fmt.Errorf("invalid IPv6 address %q", host)
+		}
+	}
+	return host, port, nil
+}
+
+func parsePort(p string) (int, error) {
+	n, err := strconv.Atoi(p)
+	if err != nil || n < 1 || n > 65535 {
+		return 0, fmt.Errorf("invalid port %q", p)
+	}
+	return n, nil
+}
+
+func parseAlgorithms(field string, a rawAlgorithms) (sshclient.Algorithms, error) {
+	out := sshclient.Algorithms{
+		KeyExchanges: trimList(a.KeyExchanges),
+		Ciphers:      trimList(a.Ciphers),
+		MACs:         trimList(a.MACs),
+		HostKeys:     trimList(a.HostKeys),
+	}
+	if err := out.Validate(); err != nil {
+		return sshclient.Algorithms{}, fmt.Errorf("%s: %w", field, err)
+	}
+	return out, nil
+}
+
+func trimList(in []string) []string {
+	var out []string
+	for _, v := range in {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// parseAuth applies auth mode defaults and validates required fields.
+// field is the yaml path used in error messages (e.g. "ssh.auth").
+func parseAuth(field string, a rawAuth) (SSHAuth, error) {
+	authMode := strings.TrimSpace(a.Mode)
+	passEnv := strings.TrimSpace(a.PasswordEnv)
+	passFile := strings.TrimSpace(a.PasswordFile)
+	keyPath := strings.TrimSpace(a.KeyPath)
+	certPath := strings.TrimSpace(a.CertPath)
+	keyPassEnv := strings.TrimSpace(a.KeyPassphraseEnv)
+	keyPassFile := strings.TrimSpace(a.KeyPassphraseFile)
+	agentSock := strings.TrimSpace(a.AgentSocket)
+	totpEnv := strings.TrimSpace(a.TOTPSeedEnv)
+	totpFile := strings.TrimSpace(a.TOTPSeedFile)
+
+	// ---- Smart default for auth mode ----
+	if authMode == "" {
+		if certPath != "" {
+			authMode = "cert"
+		} else if keyPath != "" {
+			authMode = "key"
+		} else if agentSock != "" {
+			authMode = "agent"
+		} else if passFile != "" {
+			authMode = "password_file"
+		} else {
+			authMode = "password_env"
+		}
+	}
+
+	switch authMode {
+	case "password_env":
+		if passEnv == "" {
+			return SSHAuth{}, fmt.Errorf("%s.password_env is required for password_env mode", field)
+		}
+	case "password_file":
+		if passFile == "" {
+			return SSHAuth{}, fmt.Errorf("%s.password_file is required for password_file mode", field)
+		}
+	case "key", "cert":
+		if keyPath == "" {
+			return SSHAuth{}, fmt.Errorf("%s.key_path is required for %s mode", field, authMode)
+		}
+		if authMode == "cert" && certPath == "" {
+			return SSHAuth{}, fmt.Errorf("%s.cert_path is required for cert mode", field)
+		}
+		if keyPassEnv != "" && keyPassFile != "" {
+			return SSHAuth{}, fmt.Errorf("set only one of %s.key_passphrase_env / key_passphrase_file", field)
+		}
+	case "agent":
+		// socket falls back to SSH_AUTH_SOCK at connect time
+	default:
+		return SSHAuth{}, fmt.Errorf("unsupported %s.mode %q", field, authMode)
+	}
+
+	if totpEnv != "" && totpFile != "" {
+		return SSHAuth{}, fmt.Errorf("set only one of %s.totp_seed_env / totp_seed_file", field)
+	}
+	if _, err := regexp.Compile(a.PasswordPrompt); err != nil {
+		return SSHAuth{}, fmt.Errorf("%s.password_prompt: %w", field, err)
+	}
+	if _, err := regexp.Compile(a.TOTPPrompt); err != nil {
+		return SSHAuth{}, fmt.Errorf("%s.totp_prompt: %w", field, err)
+	}
+
+	return SSHAuth{
+		Mode:         authMode,
+		PasswordEnv:  passEnv,
+		PasswordFile: passFile,
+		KeyPath:      keyPath,
+		CertPath:     certPath,
+
+		KeyPassphraseEnv:  keyPassEnv,
+		KeyPassphraseFile: keyPassFile,
+
+		AgentSocket: agentSock,
+
+		TOTPSeedEnv:    totpEnv,
+		TOTPSeedFile:   totpFile,
+		PasswordPrompt: a.PasswordPrompt,
+		TOTPPrompt:     a.TOTPPrompt,
+	}, nil
+}
@@ -0,0 +1,118 @@
+package inventory
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func loadYAML(t *testing.T, doc string) (*Inventory, error) {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), "targets.yaml")
+	if err := os.WriteFile(p, []byte(doc), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return Load(p)
+}
+
+func TestLoadDuplicateAddress(t *testing.T) {
+	_, err := loadYAML(t, `
+targets:
+  - name: web-22
+    address: 10.0.0.5
+    ssh: {user: monitor, port: 22, auth: {agent_socket: /run/agent.sock}}
+  - name: web-2222
+    address: 10.0.0.5
+    ssh: {user: monitor, port: 2222, auth: {agent_socket: /run/agent.sock}}
+`)
+	if err == nil {
+		t.Fatal("duplicate address accepted")
+	}
+	for _, want := range []string{`"web-22"`, `"web-2222"`, `"10.0.0.5"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("err = %v, want it to name %s", err, want)
+		}
+	}
+}
+
+func TestLoadSameHostDifferentPorts(t *testing.T) {
+	inv, err := loadYAML(t, `
+targets:
+  - name: web-22
+    address: 10.0.0.5
+    ssh: {user: monitor, auth: {agent_socket: /run/agent.sock}}
+  - name: web-2222
+    address: 10.0.0.5:2222
+    ssh: {user: monitor, auth: {agent_socket: /run/agent.sock}}
+`)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(inv.Targets) != 2 || inv.Targets[0].Port != 0 || inv.Targets[1].Port != 2222 {
+		t.Errorf("targets = %+v", inv.Targets)
+	}
+}
+
+func TestLoadResolvesCollectors(t *testing.T) {
+	inv, err := loadYAML(t, `
+targets:
+  - name: web-1
+    address: 10.0.0.5
+    ssh: {user: monitor, auth: {agent_socket: /run/agent.sock}}
+  - name: web-2
+    address: 10.0.0.6
+    collectors: [uptime, netdev]
+    collector_config:
+      netdev: {exclude: "^lo$"}
+    ssh: {user: monitor, auth: {agent_socket: /run/agent.sock}}
+`)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	defaults := inv.Targets[0].Collectors
+	if len(defaults) != 1 || defaults[0].Name() != "uptime" {
+		t.Errorf("default collectors = %v", defaults)
+	}
+
+	cols := inv.Targets[1].Collectors
+	if len(cols) != 2 || cols[0].Name() != "uptime" || cols[1].Name() != "netdev" {
+		t.Fatalf("collectors = %v", cols)
+	}
+	// netdev comes back configured: lo is filtered out
+	samples, err := cols[1].Collect(" face |bytes packets|bytes packets\n    lo: 1 2 3 4\n  eth0: 5 6 7 8\n")
+	if err != nil {
+		t.Fatalf("Collect: %v", err)
+	}
+	for _, s := range samples {
+		if s.Labels["device"] != "eth0" {
+			t.Errorf("sample for device %q, want only eth0", s.Labels["device"])
+		}
+	}
+	if len(samples) == 0 {
+		t.Error("no samples for eth0")
+	}
+}
+
+func TestLoadCollectorErrors(t *testing.T) {
+	for name, collectors := range map[string]string{
+		"unknown collector":               "collectors: [nope]",
+		"config for an unknown collector": `collector_config: {nope: {a: b}}`,
+		"bad regexp": `collectors: [netdev]
+    collector_config: {netdev: {include: "("}}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := loadYAML(t, `
+targets:
+  - name: web-1
+    address: 10.0.0.5
+    `+collectors+`
+    ssh: {user: monitor, auth: {agent_socket: /run/agent.sock}}
+`)
+			if err == nil || !strings.Contains(err.Error(), `target "web-1": collectors`) {
+				t.Errorf("err = %v, want a collectors error for web-1", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package inventory
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tastythames/ssh-exporter/internal/sshclient"
+)
+
+// loadSSHConfig parses the OpenSSH client config named by the inventory
+// (nil when none is set).
+func loadSSHConfig(path string) (*sshclient.ConfigFile, error) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return nil, nil
+	}
+	if strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("ssh_config: %w", err)
+		}
+		path = filepath.Join(home, path[2:])
+	}
+	return sshclient.ParseConfigFile(path)
+}
+
+// resolveHost looks alias up in cfg. Inventory values always win over the
+// file, like command line options do for ssh(1).
+func resolveHost(cfg *sshclient.ConfigFile, alias, user string) sshclient.HostConfig {
+	if cfg == nil {
+		return sshclient.HostConfig{}
+	}
+	return cfg.Resolve(alias, user)
+}
+
+// identityAuth fills key_path from IdentityFile when the inventory has no auth
+// settings at all. The first identity that exists on disk is used.
+func identityAuth(a rawAuth, hc sshclient.HostConfig) rawAuth {
+	if a != (rawAuth{}) || len(hc.IdentityFiles) == 0 {
+		return a
+	}
+	a.KeyPath = hc.IdentityFiles[0]
+	for _, f := range hc.IdentityFiles {
+		if _, err := os.Stat(f); err == nil {
+			a.KeyPath = f
+			break
+		}
+	}
+	return a
+}
+
+// proxyJumpHosts turns a ProxyJump value ("[user@]host[:port],...") into
+// jump hosts. Each hop is resolved through cfg as well; hops without an
+// IdentityFile authenticate with ssh-agent.
+func proxyJumpHosts(cfg *sshclient.ConfigFile, proxyJump, defaultUser string) ([]JumpHost, error) {
+	if proxyJump == "" || strings.EqualFold(proxyJump, "none") {
+		return nil, nil
+	}
+
+	var out []JumpHost
+	for _, spec := range strings.Split(proxyJump, ",") {
+		spec = strings.TrimSpace(strings.TrimPrefix(spec, "ssh://"))
+		if spec == "" {
+			continue
+		}
+		user := ""
+		if i := strings.LastIndex(spec, "@"); i >= 0 {
+			user, spec = spec[:i], spec[i+1:]
+		}
+		alias, port, err := splitAddress(spec)
+		if err != nil {
+			return nil, fmt.Errorf("ProxyJump %q: %w", spec, err)
+		}
+
+		hc := resolveHost(cfg, alias, user)
+		host := alias
+		if hc.HostName != "" {
+			host = hc.HostName
+		}
+		if port == 0 {
+			port = hc.Port
+		}
+		if user == "" {
+			user = hc.User
+		}
+		if user == "" {
+			user = defaultUser
+		}
+
+		auth := SSHAuth{Mode: "agent"}
+		if ra := identityAuth(rawAuth{}, hc); ra.KeyPath != "" {
+			auth = SSHAuth{Mode: "key", KeyPath: ra.KeyPath}
+		}
+
+		out = append(out, JumpHost{
+			Host:            host,
+			Port:            port,
+			User:            user,
+			Auth:            auth,
+			KnownHostsFiles: hc.UserKnownHostsFiles,
+		})
+	}
+	return out, nil
+}
@@ -8,11 +8,66 @@ const (
 	MetricTargetUp       = "ssh_target_up"
 	MetricScrapeDuration = "ssh_target_scrape_duration_seconds"
 	MetricLastScrapeTs   = "ssh_target_last_scrape_timestamp_seconds"
+	MetricPhaseDuration  = "ssh_target_phase_duration_seconds"
 
 	// cache + render
 	MetricCacheAgeSeconds       = "ssh_target_scrape_cache_age_seconds"
 	MetricRenderDurationSeconds = "ssh_exporter_render_duration_seconds"
 
+	// ssh connection pool
+	MetricPoolConnections = "ssh_exporter_pool_connections"
+	MetricPoolDials       = "ssh_exporter_pool_dials_total"
+	MetricPoolReused      = "ssh_exporter_pool_reused_total"
+	MetricPoolReconnects  = "ssh_exporter_pool_reconnects_total"
+
 	// error flag
 	MetricTargetError = "ssh_target_error"
+
+	// circuit breaker
+	MetricConsecutiveFailures = "ssh_target_consecutive_failures"
+	MetricBackoffSeconds      = "ssh_target_backoff_seconds"
+
+	// remote command exit status
+	MetricCommandExitCode = "ssh_command_exit_code"
+
+	// host key
+	MetricHostKeyChanged = "ssh_target_hostkey_changed"
+	MetricHostKeyInfo    = "ssh_target_hostkey_info"
+
+	// certificates
+	MetricHostCertExpiry = "ssh_target_hostkey_cert_expiry_timestamp_seconds"
+	MetricUserCertExpiry = "ssh_target_user_cert_expiry_timestamp_seconds"
+
+	// negotiated crypto
+	MetricCryptoInfo = "ssh_target_crypto_info"
 )
+
+// Reasons for ssh_target_error{reason=...}; every reason is exported per
+// target (1 for the current one, 0 otherwise) so alerts can match on it.
+const (
+	ReasonDNS             = "dns"
+	ReasonRefused         = "refused"
+	ReasonTimeout         = "timeout"
+	ReasonUnreachable     = "unreachable"
+	ReasonHostKeyMismatch = "hostkey_mismatch"
+	ReasonHostKeyUnknown  = "hostkey_unknown"
+	ReasonAuthFailed      = "auth_failed"
+	ReasonSecretMissing   = "secret_missing"
+	ReasonCommandFailed   = "command_failed"
+	ReasonParseError      = "parse_error"
+	ReasonOther           = "other"
+)
+
+var ErrorReasons = []string{
+	ReasonDNS,
+	ReasonRefused,
+	ReasonTimeout,
+	ReasonUnreachable,
+	ReasonHostKeyMismatch,
+	ReasonHostKeyUnknown,
+	ReasonAuthFailed,
+	ReasonSecretMissing,
+	ReasonCommandFailed,
+	ReasonParseError,
+	ReasonOther,
+}
@@ -8,10 +8,17 @@ import (
 	"time"
 
 	"github.com/tastythames/ssh-exporter/internal/cache"
+	"github.com/tastythames/ssh-exporter/internal/sshclient"
 )
 
+// PoolSource is implemented by *sshclient.Client.
+type PoolSource interface {
+	PoolStats() sshclient.PoolStats
+}
+
 type Renderer struct {
 	Cache cache.Cache
+	Pool  PoolSource // optional
 }
 
 func NewRenderer(c cache.Cache) *Renderer {
@@ -32,6 +39,26 @@ func (r *Renderer) Write(w io.Writer) {
 	fmt.Fprintf(w, "# HELP %s Time spent rendering /metrics.\n", MetricRenderDurationSeconds)
 	fmt.Fprintf(w, "# TYPE %s gauge\n", MetricRenderDurationSeconds)
 
+	if r.Pool != nil {
+		ps := r.Pool.PoolStats()
+
+		fmt.Fprintf(w, "# HELP %s Open pooled SSH connections (targets and bastions).\n", MetricPoolConnections)
+		fmt.Fprintf(w, "# TYPE %s gauge\n", MetricPoolConnections)
+		fmt.Fprintf(w, "%s %d\n", MetricPoolConnections, ps.Connections)
+
+		fmt.Fprintf(w, "# HELP %s SSH connections established by the pool.\n", MetricPoolDials)
+		fmt.Fprintf(w, "# TYPE %s counter\n", MetricPoolDials)
+		fmt.Fprintf(w, "%s %d\n", MetricPoolDials, ps.Dials)
+
+		fmt.Fprintf(w, "# HELP %s Times an existing pooled connection was reused.\n", MetricPoolReused)
+		fmt.Fprintf(w, "# TYPE %s counter\n", MetricPoolReused)
+		fmt.Fprintf(w, "%s %d\n", MetricPoolReused, ps.Reused)
+
+		fmt.Fprintf(w, "# HELP %s Connections re-established after the previous one went bad.\n", MetricPoolReconnects)
+		fmt.Fprintf(w, "# TYPE %s counter\n", MetricPoolReconnects)
+		fmt.Fprintf(w, "%s %d\n", MetricPoolReconnects, ps.Reconnects)
+	}
+
 	// ---------------------------------------------------
 	// Target metrics (headers)
 	// ---------------------------------------------------
@@ -44,12 +71,39 @@ func (r *Renderer) Write(w io.Writer) {
 	fmt.Fprintf(w, "# HELP %s Duration of SSH scrape.\n", MetricScrapeDuration)
 	fmt.Fprintf(w, "# TYPE %s gauge\n", MetricScrapeDuration)
 
+	fmt.Fprintf(w, "# HELP %s Time spent per SSH phase in the last scrape (connect, kex, auth, session, command).\n", MetricPhaseDuration)
+	fmt.Fprintf(w, "# TYPE %s gauge\n", MetricPhaseDuration)
+
 	fmt.Fprintf(w, "# HELP %s Unix timestamp of last scrape.\n", MetricLastScrapeTs)
 	fmt.Fprintf(w, "# TYPE %s gauge\n", MetricLastScrapeTs)
 
-	fmt.Fprintf(w, "# HELP %s 1 if last scrape returned error.\n", MetricTargetError)
+	fmt.Fprintf(w, "# HELP %s 1 if last scrape failed for this reason.\n", MetricTargetError)
 	fmt.Fprintf(w, "# TYPE %s gauge\n", MetricTargetError)
 
+	fmt.Fprintf(w, "# HELP %s Scrapes in a row that could not reach or log into the target.\n", MetricConsecutiveFailures)
+	fmt.Fprintf(w, "# TYPE %s gauge\n", MetricConsecutiveFailures)
+
+	fmt.Fprintf(w, "# HELP %s Current backoff period while the target is skipped (0 = scraped normally).\n", MetricBackoffSeconds)
+	fmt.Fprintf(w, "# TYPE %s gauge\n", MetricBackoffSeconds)
+
+	fmt.Fprintf(w, "# HELP %s Exit status of the last command run for each collector (-1 if none was sent).\n", MetricCommandExitCode)
+	fmt.Fprintf(w, "# TYPE %s gauge\n", MetricCommandExitCode)
+
+	fmt.Fprintf(w, "# HELP %s 1 if the target presented a host key that differs from the trusted one.\n", MetricHostKeyChanged)
+	fmt.Fprintf(w, "# TYPE %s gauge\n", MetricHostKeyChanged)
+
+	fmt.Fprintf(w, "# HELP %s Host key presented by the target (value is always 1).\n", MetricHostKeyInfo)
+	fmt.Fprintf(w, "# TYPE %s gauge\n", MetricHostKeyInfo)
+
+	fmt.Fprintf(w, "# HELP %s Unix timestamp when the host certificate expires.\n", MetricHostCertExpiry)
+	fmt.Fprintf(w, "# TYPE %s gauge\n", MetricHostCertExpiry)
+
+	fmt.Fprintf(w, "# HELP %s Unix timestamp when the user certificate used for the target expires.\n", MetricUserCertExpiry)
+	fmt.Fprintf(w, "# TYPE %s gauge\n", MetricUserCertExpiry)
+
+	fmt.Fprintf(w, "# HELP %s Algorithms negotiated with the target (value is always 1).\n", MetricCryptoInfo)
+	fmt.Fprintf(w, "# TYPE %s gauge\n", MetricCryptoInfo)
+
 	// ---------------------------------------------------
 	// Snapshot cache
 	// ---------------------------------------------------
@@ -61,6 +115,7 @@ func (r *Renderer) Write(w io.Writer) {
 	}
 	sort.Strings(targets)
 
+	fams := make(map[string]*familyOut)
 	for _, t := range targets {
 		res := snap[t]
 
@@ -73,23 +128,142 @@ func (r *Renderer) Write(w io.Writer) {
 		age := now.Sub(res.At).Seconds()
 		fmt.Fprintf(w, "%s%s %.3f\n", MetricCacheAgeSeconds, formatLabels(labels), age)
 
+		// scrape timing
+		fmt.Fprintf(w, "%s%s %.6f\n", MetricScrapeDuration, formatLabels(labels), res.Duration.Seconds())
+		fmt.Fprintf(w, "%s%s %d\n", MetricLastScrapeTs, formatLabels(labels), res.At.Add(res.Duration).Unix())
+		if !res.UserCertExpiry.IsZero() {
+			fmt.Fprintf(w, "%s%s %d\n", MetricUserCertExpiry, formatLabels(labels), res.UserCertExpiry.Unix())
+		}
+
 		// up + error
 		up := 1.0
-		errFlag := 0.0
+		reason := ""
 		if res.Err != nil {
 			up = 0
-			errFlag = 1
+			reason = res.ErrReason
+			if reason == "" {
+				reason = ReasonOther
+			}
 		}
 		fmt.Fprintf(w, "%s%s %.0f\n", MetricTargetUp, formatLabels(labels), up)
-		fmt.Fprintf(w, "%s%s %.0f\n", MetricTargetError, formatLabels(labels), errFlag)
+		for _, rsn := range ErrorReasons {
+			el := map[string]string{"reason": rsn}
+			for k, v := range labels {
+				el[k] = v
+			}
+			errFlag := 0
+			if rsn == reason {
+				errFlag = 1
+			}
+			fmt.Fprintf(w, "%s%s %d\n", MetricTargetError, formatLabels(el), errFlag)
+		}
 
-		// extra values from worker
-		for name, val := range res.Values {
-			// avoid duplicates + avoid exporter-level metrics
-			if name == MetricTargetUp || name == MetricExporterUp || name == MetricTargetError {
-				continue
+		// circuit breaker
+		fmt.Fprintf(w, "%s%s %d\n", MetricConsecutiveFailures, formatLabels(labels), res.ConsecutiveFailures)
+		fmt.Fprintf(w, "%s%s %.0f\n", MetricBackoffSeconds, formatLabels(labels), res.Backoff.Seconds())
+
+		// command exit status
+		collectors := make([]string, 0, len(res.ExitCodes))
+		for col := range res.ExitCodes {
+			collectors = append(collectors, col)
+		}
+		sort.Strings(collectors)
+		for _, col := range collectors {
+			cl := map[string]string{"collector": col}
+			for k, v := range labels {
+				cl[k] = v
+			}
+			fmt.Fprintf(w, "%s%s %d\n", MetricCommandExitCode, formatLabels(cl), res.ExitCodes[col])
+		}
+
+		// per-phase timing
+		phases := make([]string, 0, len(res.Phases))
+		for p := range res.Phases {
+			phases = append(phases, p)
+		}
+		sort.Strings(phases)
+		for _, p := range phases {
+			pl := map[string]string{"phase": p}
+			for k, v := range labels {
+				pl[k] = v
+			}
+			fmt.Fprintf(w, "%s%s %.6f\n", MetricPhaseDuration, formatLabels(pl), res.Phases[p])
+		}
+
+		// host key
+		if hk := res.HostKey; hk != nil {
+			changed := 0.0
+			if hk.Changed {
+				changed = 1
+			}
+			fmt.Fprintf(w, "%s%s %.0f\n", MetricHostKeyChanged, formatLabels(labels), changed)
+
+			info := map[string]string{"key_type": hk.Type, "fingerprint": hk.Fingerprint}
+			for k, v := range labels {
+				info[k] = v
+			}
+			fmt.Fprintf(w, "%s%s 1\n", MetricHostKeyInfo, formatLabels(info))
+
+			if !hk.CertValidBefore.IsZero() {
+				fmt.Fprintf(w, "%s%s %d\n", MetricHostCertExpiry, formatLabels(labels), hk.CertValidBefore.Unix())
+			}
+		}
+
+		// negotiated crypto
+		if cr := res.Crypto; cr != nil {
+			info := map[string]string{
+				"kex":        cr.KeyExchange,
+				"host_key":   cr.HostKey,
+				"cipher_c2s": cr.CipherClientServer,
+				"cipher_s2c": cr.CipherServerClient,
+				"mac_c2s":    cr.MACClientServer,
+				"mac_s2c":    cr.MACServerClient,
+				"weak":       fmt.Sprintf("%t", cr.Weak),
+			}
+			for k, v := range labels {
+				info[k] = v
+			}
+			fmt.Fprintf(w, "%s%s 1\n", MetricCryptoInfo, formatLabels(info))
+		}
+
+		// collector families, grouped by name across targets below
+		for _, f := range res.Families {
+			fo, ok := fams[f.Name]
+			if !ok {
+				fo = &familyOut{help: f.Help, typ: f.Type}
+				fams[f.Name] = fo
+			}
+			for _, sm := range f.Samples {
+				sl := make(map[string]string, len(labels)+len(sm.Labels))
+				for k, v := range labels {
+					sl[k] = v
+				}
+				for k, v := range sm.Labels {
+					sl[k] = v
+				}
+				fo.lines = append(fo.lines, fmt.Sprintf("%s%s %v", f.Name, formatLabels(sl), sm.Value))
 			}
-			fmt.Fprintf(w, "%s%s %v\n", name, formatLabels(labels), val)
+		}
+	}
+
+	// every sample of a family must follow its HELP/TYPE lines
+	names := make([]string, 0, len(fams))
+	for n := range fams {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		fo := fams[n]
+		typ := fo.typ
+		if typ == "" {
+			typ = cache.Gauge
+		}
+		if fo.help != "" {
+			fmt.Fprintf(w, "# HELP %s %s\n", n, escapeHelp(fo.help))
+		}
+		fmt.Fprintf(w, "# TYPE %s %s\n", n, typ)
+		for _, ln := range fo.lines {
+			fmt.Fprintln(w, ln)
 		}
 	}
 
@@ -114,9 +288,22 @@ func formatLabels(m map[string]string) string {
 		if i > 0 {
 			b.WriteString(",")
 		}
-		// NOTE: keep simple (labels should not contain quotes in this lab)
-		fmt.Fprintf(&b, `%s="%s"`, k, m[k])
+		fmt.Fprintf(&b, `%s="%s"`, k, labelEscaper.Replace(m[k]))
 	}
 	b.WriteString("}")
 	return b.String()
 }
+
+// familyOut collects the rendered samples of one collector family.
+type familyOut struct {
+	help  string
+	typ   cache.MetricType
+	lines []string
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeHelp(s string) string { return helpEscaper.Replace(s) }
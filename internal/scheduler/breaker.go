@@ -0,0 +1,95 @@
+package scheduler
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// BreakerOptions configures per-target backoff.
+// - Threshold: consecutive failures before a target is skipped (default 3)
+// - BaseBackoff: first skip period; doubled on every further failure
+// - MaxBackoff: cap for the skip period (default 10m)
+type BreakerOptions struct {
+	Threshold   int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+}
+
+// Breaker is a circuit breaker per target: after Threshold consecutive
+// failures the scheduler stops enqueueing the target until its backoff
+// expires, so dead hosts do not hold workers for a full timeout each cycle.
+// One success closes it again.
+type Breaker struct {
+	opts BreakerOptions
+
+	mu     sync.Mutex
+	states map[string]*breakerState
+}
+
+type breakerState struct {
+	failures int
+	backoff  time.Duration
+	until    time.Time
+}
+
+func NewBreaker(opts BreakerOptions) *Breaker {
+	if opts.Threshold <= 0 {
+		opts.Threshold = 3
+	}
+	if opts.BaseBackoff <= 0 {
+		opts.BaseBackoff = 10 * time.Second
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = 10 * time.Minute
+	}
+	if opts.MaxBackoff < opts.BaseBackoff {
+		opts.MaxBackoff = opts.BaseBackoff
+	}
+	return &Breaker{opts: opts, states: make(map[string]*breakerState)}
+}
+
+// Allow reports whether target may be scraped now.
+func (b *Breaker) Allow(target string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	st, ok := b.states[target]
+	return !ok || !time.Now().Before(st.until)
+}
+
+// Record updates target with the outcome of a scrape and returns its
+// consecutive failures and current backoff (0 when not backing off).
+func (b *Breaker) Record(target string, err error) (int, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err == nil {
+		if st, ok := b.states[target]; ok && st.backoff > 0 {
+			log.Printf("breaker: target=%s recovered after %d failures", target, st.failures)
+		}
+		delete(b.states, target)
+		return 0, 0
+	}
+
+	st, ok := b.states[target]
+	if !ok {
+		st = &breakerState{}
+		b.states[target] = st
+	}
+	st.failures++
+	if st.failures < b.opts.Threshold {
+		return st.failures, 0
+	}
+
+	backoff := b.opts.BaseBackoff
+	for i := b.opts.Threshold; i < st.failures && backoff < b.opts.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > b.opts.MaxBackoff {
+		backoff = b.opts.MaxBackoff
+	}
+	st.backoff = backoff
+	st.until = time.Now().Add(backoff)
+	log.Printf("breaker: target=%s failures=%d backoff=%s", target, st.failures, backoff)
+	return st.failures, backoff
+}
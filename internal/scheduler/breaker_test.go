@@ -0,0 +1,108 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestBreakerBackoff(t *testing.T) {
+	b := NewBreaker(BreakerOptions{Threshold: 3, BaseBackoff: 10 * time.Second, MaxBackoff: 60 * time.Second})
+	fail := errors.New("dial tcp: connection refused")
+
+	// failures, backoff after each consecutive failure: doubling from the
+	// threshold on, capped at MaxBackoff
+	want := []struct {
+		failures int
+		backoff  time.Duration
+	}{
+		{1, 0},
+		{2, 0},
+		{3, 10 * time.Second},
+		{4, 20 * time.Second},
+		{5, 40 * time.Second},
+		{6, 60 * time.Second},
+		{7, 60 * time.Second},
+	}
+	for _, w := range want {
+		failures, backoff := b.Record("h1", fail)
+		if failures != w.failures || backoff != w.backoff {
+			t.Fatalf("Record = %d, %s; want %d, %s", failures, backoff, w.failures, w.backoff)
+		}
+		if allowed := b.Allow("h1"); allowed != (w.backoff == 0) {
+			t.Fatalf("after %d failures Allow = %v", w.failures, allowed)
+		}
+	}
+
+	// other targets are unaffected
+	if !b.Allow("h2") {
+		t.Error("Allow(h2) = false for a target with no failures")
+	}
+
+	// one success resets the count and the backoff
+	if failures, backoff := b.Record("h1", nil); failures != 0 || backoff != 0 {
+		t.Errorf("Record(success) = %d, %s; want 0, 0", failures, backoff)
+	}
+	if !b.Allow("h1") {
+		t.Error("Allow = false after a success")
+	}
+	if failures, backoff := b.Record("h1", fail); failures != 1 || backoff != 0 {
+		t.Errorf("Record after reset = %d, %s; want 1, 0", failures, backoff)
+	}
+}
+
+func TestBreakerAllowExpires(t *testing.T) {
+	b := NewBreaker(BreakerOptions{Threshold: 1, BaseBackoff: time.Minute})
+	b.Record("h1", errors.New("timeout"))
+	if b.Allow("h1") {
+		t.Fatal("Allow = true while backing off")
+	}
+
+	b.mu.Lock()
+	b.states["h1"].until = time.Now().Add(-time.Second)
+	b.mu.Unlock()
+	if !b.Allow("h1") {
+		t.Error("Allow = false after the backoff expired")
+	}
+
+	// a failure of the retry backs off for twice as long
+	if _, backoff := b.Record("h1", errors.New("timeout")); backoff != 2*time.Minute {
+		t.Errorf("backoff = %s, want 2m", backoff)
+	}
+}
+
+func TestBreakerDefaults(t *testing.T) {
+	b := NewBreaker(BreakerOptions{BaseBackoff: time.Hour, MaxBackoff: time.Minute})
+	if b.opts.Threshold != 3 {
+		t.Errorf("Threshold = %d, want 3", b.opts.Threshold)
+	}
+	if b.opts.MaxBackoff != time.Hour {
+		t.Errorf("MaxBackoff = %s, want it raised to BaseBackoff", b.opts.MaxBackoff)
+	}
+}
+
+func TestTripsBreaker(t *testing.T) {
+	hung := fmt.Errorf("%w: ssh: handshake failed: %w", context.DeadlineExceeded, &net.OpError{Op: "read", Net: "tcp", Err: net.ErrClosed})
+	behindBastion := &ssh.OpenChannelError{Reason: ssh.ConnectionFailed, Message: "Connection refused"}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"hung sshd", hung, true},
+		{"dead host behind bastion", behindBastion, true},
+		{"command failed", &CommandError{Collector: "uptime"}, false},
+		{"parse error", &ParseError{What: "uptime", Err: errors.New("bad")}, false},
+	}
+	for _, tt := range tests {
+		if got := tripsBreaker(errorReason(tt.err)); got != tt.want {
+			t.Errorf("%s: tripsBreaker(%q) = %v, want %v", tt.name, errorReason(tt.err), got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/tastythames/ssh-exporter/internal/cache"
+	"github.com/tastythames/ssh-exporter/internal/metrics"
+	"github.com/tastythames/ssh-exporter/internal/sshclient"
+)
+
+func setErr(res *cache.Result, err error) {
+	res.Err = err
+	res.ErrReason = errorReason(err)
+}
+
+// tripsBreaker reports whether an error with reason means the host could
+// not be reached or logged into. A host that answers but whose command or
+// parser fails is not backed off: its other collectors still work.
+func tripsBreaker(reason string) bool {
+	switch reason {
+	case metrics.ReasonDNS,
+		metrics.ReasonRefused,
+		metrics.ReasonTimeout,
+		metrics.ReasonUnreachable,
+		metrics.ReasonAuthFailed,
+		metrics.ReasonHostKeyMismatch,
+		metrics.ReasonHostKeyUnknown:
+		return true
+	}
+	return false
+}
+
+// errorReason maps a scrape error onto one of metrics.ErrorReasons.
+// Order matters: more specific checks come first.
+func errorReason(err error) string {
+	var (
+		secretErr *SecretError
+		parseErr  *ParseError
+		cmdErr    *CommandError
+		dnsErr    *net.DNSError
+		exitErr   *ssh.ExitError
+		exitMiss  *ssh.ExitMissingError
+		chanErr   *ssh.OpenChannelError
+		netErr    net.Error
+	)
+
+	switch {
+	case err == nil:
+		return ""
+
+	case errors.As(err, &secretErr),
+		errors.Is(err, sshclient.ErrKeyUnreadable),
+		errors.Is(err, sshclient.ErrKeyPassphrase):
+		return metrics.ReasonSecretMissing
+
+	case errors.Is(err, sshclient.ErrHostKeyMismatch),
+		errors.Is(err, sshclient.ErrHostKeyRevoked):
+		return metrics.ReasonHostKeyMismatch
+
+	case errors.Is(err, sshclient.ErrHostKeyUnknown),
+		errors.Is(err, sshclient.ErrHostCertInvalid):
+		return metrics.ReasonHostKeyUnknown
+
+	case errors.Is(err, sshclient.ErrAuthFailed),
+		errors.Is(err, sshclient.ErrKeyUnsupported):
+		return metrics.ReasonAuthFailed
+
+	case errors.As(err, &parseErr):
+		return metrics.ReasonParseError
+
+	case errors.As(err, &cmdErr), errors.As(err, &exitErr), errors.As(err, &exitMiss):
+		return metrics.ReasonCommandFailed
+
+	case errors.As(err, &dnsErr):
+		return metrics.ReasonDNS
+
+	case errors.As(err, &chanErr): // a bastion could not open the next hop
+		return openChannelReason(chanErr)
+
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return metrics.ReasonRefused
+
+	case errors.Is(err, context.DeadlineExceeded),
+		errors.Is(err, os.ErrDeadlineExceeded),
+		errors.As(err, &netErr) && netErr.Timeout():
+		return metrics.ReasonTimeout
+
+	case errors.Is(err, syscall.EHOSTUNREACH),
+		errors.Is(err, syscall.ENETUNREACH):
+		return metrics.ReasonUnreachable
+
+	default:
+		return metrics.ReasonOther
+	}
+}
+
+// openChannelReason classifies a bastion's refusal to forward. OpenSSH
+// reports connect failures as ConnectionFailed with strerror as the message
+// ("Connection refused", "Connection timed out", "No route to host").
+func openChannelReason(e *ssh.OpenChannelError) string {
+	if e.Reason != ssh.ConnectionFailed {
+		return metrics.ReasonUnreachable // administratively prohibited etc.
+	}
+	msg := strings.ToLower(e.Message)
+	switch {
+	case strings.Contains(msg, "refused"):
+		return metrics.ReasonRefused
+	case strings.Contains(msg, "timed out"), strings.Contains(msg, "timeout"):
+		return metrics.ReasonTimeout
+	default:
+		return metrics.ReasonUnreachable
+	}
+}
@@ -0,0 +1,64 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/tastythames/ssh-exporter/internal/metrics"
+	"github.com/tastythames/ssh-exporter/internal/sshclient"
+)
+
+func TestErrorReason(t *testing.T) {
+	// what connect returns when the ctx close beats the conn deadline
+	closedConn := fmt.Errorf("ssh: handshake failed: %w", &net.OpError{Op: "read", Net: "tcp", Err: net.ErrClosed})
+	dialOp := func(err error) error {
+		return &net.OpError{Op: "dial", Net: "tcp", Err: &os.SyscallError{Syscall: "connect", Err: err}}
+	}
+	bastion := func(reason ssh.RejectionReason, msg string) error {
+		return fmt.Errorf("dial 10.0.0.5:22 via bastion: %w", &ssh.OpenChannelError{Reason: reason, Message: msg})
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"nil", nil, ""},
+		{"dns", &net.DNSError{Err: "no such host", Name: "nope.example", IsNotFound: true}, metrics.ReasonDNS},
+		{"refused", dialOp(syscall.ECONNREFUSED), metrics.ReasonRefused},
+		{"host unreachable", dialOp(syscall.EHOSTUNREACH), metrics.ReasonUnreachable},
+		{"net unreachable", dialOp(syscall.ENETUNREACH), metrics.ReasonUnreachable},
+		{"deadline", context.DeadlineExceeded, metrics.ReasonTimeout},
+		{"conn deadline", &net.OpError{Op: "read", Net: "tcp", Err: os.ErrDeadlineExceeded}, metrics.ReasonTimeout},
+		{"handshake closed on ctx", fmt.Errorf("%w: %w", context.DeadlineExceeded, closedConn), metrics.ReasonTimeout},
+		{"handshake closed otherwise", closedConn, metrics.ReasonOther},
+		{"bastion refused", bastion(ssh.ConnectionFailed, "Connection refused"), metrics.ReasonRefused},
+		{"bastion timed out", bastion(ssh.ConnectionFailed, "Connection timed out"), metrics.ReasonTimeout},
+		{"bastion no route", bastion(ssh.ConnectionFailed, "No route to host"), metrics.ReasonUnreachable},
+		{"bastion connect failed", bastion(ssh.ConnectionFailed, "connect failed"), metrics.ReasonUnreachable},
+		{"bastion prohibited", bastion(ssh.Prohibited, "administratively prohibited"), metrics.ReasonUnreachable},
+		{"auth", fmt.Errorf("%w: ssh: unable to authenticate", sshclient.ErrAuthFailed), metrics.ReasonAuthFailed},
+		{"auth after deadline", fmt.Errorf("%w: %w", context.DeadlineExceeded, sshclient.ErrAuthFailed), metrics.ReasonAuthFailed},
+		{"host key mismatch", sshclient.ErrHostKeyMismatch, metrics.ReasonHostKeyMismatch},
+		{"host key unknown", sshclient.ErrHostKeyUnknown, metrics.ReasonHostKeyUnknown},
+		{"key passphrase", sshclient.ErrKeyPassphrase, metrics.ReasonSecretMissing},
+		{"secret", &SecretError{S: "env SSH_PASS unset"}, metrics.ReasonSecretMissing},
+		{"command", &CommandError{Collector: "uptime"}, metrics.ReasonCommandFailed},
+		{"parse", &ParseError{What: "uptime", Err: errors.New("bad")}, metrics.ReasonParseError},
+		{"other", errors.New("something else"), metrics.ReasonOther},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errorReason(tt.err); got != tt.want {
+				t.Errorf("errorReason(%v) = %q, want %q", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package scheduler
+
+import (
+	"log"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// LimitOptions caps how many jobs run at once against shared infrastructure.
+// Zero values disable a limit.
+// - PerBastion: jobs through the same jump host (every hop of the chain counts)
+// - Label + PerLabel: jobs with the same value of that label (e.g. "site")
+// - SubnetBits + PerSubnet: jobs whose target IPv4 is in the same /SubnetBits
+// Hostnames and IPv6 targets are not grouped by subnet.
+// - Wait: longest a job stays parked for its slots before it is dropped
+// (default 10s)
+// - DefaultPort: port of hops without one (SSH_PORT, default 22), so
+// "bastion" and "bastion:22" share a group
+type LimitOptions struct {
+	PerBastion int
+
+	Label    string
+	PerLabel int
+
+	SubnetBits int
+	PerSubnet  int
+
+	Wait        time.Duration
+	DefaultPort int
+}
+
+// Limiter hands out per-key slots; a job holds one slot for every group it
+// belongs to for the whole scrape, so a queue burst cannot turn into a burst
+// of handshakes against one bastion or rack.
+//
+// It never blocks: a job whose slots are taken is parked, and the worker
+// that frees them picks it up via Next. Workers stay free for targets in
+// other groups meanwhile.
+type Limiter struct {
+	opts LimitOptions
+
+	mu     sync.Mutex
+	used   map[string]int // group key -> running jobs
+	parked []parkedJob    // FIFO
+}
+
+type parkedJob struct {
+	job    Job
+	groups []limitGroup
+	since  time.Time
+}
+
+func NewLimiter(opts LimitOptions) *Limiter {
+	if opts.Wait <= 0 {
+		opts.Wait = 10 * time.Second
+	}
+	if opts.DefaultPort <= 0 {
+		opts.DefaultPort = 22
+	}
+	return &Limiter{opts: opts, used: make(map[string]int)}
+}
+
+// Acquire takes job's slots and returns the func that frees them. ok is
+// false when a slot is taken; job is then parked for Next and the caller
+// moves on. A nil Limiter always admits.
+func (l *Limiter) Acquire(job Job) (release func(), ok bool) {
+	if l == nil {
+		return func() {}, true
+	}
+
+	groups := l.groups(job)
+	if len(groups) == 0 {
+		return func() {}, true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.tryLocked(groups) {
+		return l.releaser(groups), true
+	}
+	// a newer job for the same target replaces the parked one
+	for i, p := range l.parked {
+		if p.job.Target == job.Target {
+			l.parked = append(l.parked[:i], l.parked[i+1:]...)
+			break
+		}
+	}
+	l.parked = append(l.parked, parkedJob{job: job, groups: groups, since: time.Now()})
+	return nil, false
+}
+
+// Next returns the oldest parked job whose slots are free now, with its
+// slots taken. Workers call it after every release so parked jobs run as
+// soon as they can. Jobs parked longer than Wait are dropped: the scheduler
+// queues a fresh one next interval.
+func (l *Limiter) Next() (job Job, release func(), ok bool) {
+	if l == nil {
+		return Job{}, nil, false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	for i := 0; i < len(l.parked); i++ {
+		p := l.parked[i]
+		if now.Sub(p.since) > l.opts.Wait {
+			log.Printf("limiter: target=%s dropped after waiting %s for a slot", p.job.Target, now.Sub(p.since).Round(time.Millisecond))
+			l.parked = append(l.parked[:i], l.parked[i+1:]...)
+			i--
+			continue
+		}
+		if l.tryLocked(p.groups) {
+			l.parked = append(l.parked[:i], l.parked[i+1:]...)
+			return p.job, l.releaser(p.groups), true
+		}
+	}
+	return Job{}, nil, false
+}
+
+// tryLocked takes a slot in every group, or none if one is full.
+func (l *Limiter) tryLocked(groups []limitGroup) bool {
+	for _, g := range groups {
+		if l.used[g.key] >= g.max {
+			return false
+		}
+	}
+	for _, g := range groups {
+		l.used[g.key]++
+	}
+	return true
+}
+
+func (l *Limiter) releaser(groups []limitGroup) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			for _, g := range groups {
+				if l.used[g.key]--; l.used[g.key] <= 0 {
+					delete(l.used, g.key)
+				}
+			}
+		})
+	}
+}
+
+// limitGroup is one set of jobs sharing a limit.
+type limitGroup struct {
+	key string
+	max int
+}
+
+// groups returns the distinct groups of job (the same bastion twice in a
+// chain counts once).
+func (l *Limiter) groups(job Job) []limitGroup {
+	var groups []limitGroup
+	if l.opts.PerBastion > 0 {
+		for _, h := range job.Jump {
+			port := h.Port
+			if port == 0 {
+				port = l.opts.DefaultPort
+			}
+			groups = append(groups, limitGroup{"bastion " + net.JoinHostPort(h.Host, strconv.Itoa(port)), l.opts.PerBastion})
+		}
+	}
+	if l.opts.PerLabel > 0 && l.opts.Label != "" {
+		if v, ok := job.Labels[l.opts.Label]; ok {
+			groups = append(groups, limitGroup{"label " + l.opts.Label + "=" + v, l.opts.PerLabel})
+		}
+	}
+	if l.opts.PerSubnet > 0 && l.opts.SubnetBits > 0 && l.opts.SubnetBits <= 32 {
+		if ip := net.ParseIP(job.Host).To4(); ip != nil {
+			mask := net.CIDRMask(l.opts.SubnetBits, 32)
+			n := net.IPNet{IP: ip.Mask(mask), Mask: mask}
+			groups = append(groups, limitGroup{"subnet " + n.String(), l.opts.PerSubnet})
+		}
+	}
+
+	sort.Slice(groups, func(i, j int) bool { return groups[i].key < groups[j].key })
+	out := groups[:0]
+	for i, g := range groups {
+		if i > 0 && groups[i-1].key == g.key {
+			continue
+		}
+		out = append(out, g)
+	}
+	return out
+}
@@ -0,0 +1,117 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func viaBastion(target string, port int) Job {
+	return Job{Target: target, Host: target, Jump: []Hop{{Host: "bastion", Port: port}}}
+}
+
+func TestLimiterParksAndHandsOff(t *testing.T) {
+	l := NewLimiter(LimitOptions{PerBastion: 1})
+
+	release, ok := l.Acquire(viaBastion("a", 0))
+	if !ok {
+		t.Fatal("first job not admitted")
+	}
+	// port 0 is SSH_PORT: same bastion, so b has to wait
+	if _, ok := l.Acquire(viaBastion("b", 22)); ok {
+		t.Fatal("second job through the same bastion admitted")
+	}
+	// a job sharing no group is not held up by the parked one
+	if rel, ok := l.Acquire(viaBastion("c", 2222)); !ok {
+		t.Fatal("job through another bastion port not admitted")
+	} else {
+		rel()
+	}
+
+	if _, _, ok := l.Next(); ok {
+		t.Fatal("Next handed out a job while its slot is taken")
+	}
+	release()
+	release() // idempotent
+
+	job, release, ok := l.Next()
+	if !ok || job.Target != "b" {
+		t.Fatalf("Next = %q, %v; want b", job.Target, ok)
+	}
+	if _, _, ok := l.Next(); ok {
+		t.Fatal("Next handed out the same job twice")
+	}
+	release()
+	if rel, ok := l.Acquire(viaBastion("d", 0)); !ok {
+		t.Fatal("slot not freed after release")
+	} else {
+		rel()
+	}
+}
+
+func TestLimiterParkedReplacedAndExpired(t *testing.T) {
+	l := NewLimiter(LimitOptions{PerBastion: 1, Wait: time.Minute})
+
+	release, _ := l.Acquire(viaBastion("a", 0))
+	old := viaBastion("b", 0)
+	old.Labels = map[string]string{"gen": "old"}
+	l.Acquire(old)
+	l.Acquire(viaBastion("b", 0)) // newer job for b replaces the parked one
+	l.Acquire(viaBastion("c", 0))
+
+	if len(l.parked) != 2 || l.parked[0].job.Target != "b" || l.parked[0].job.Labels != nil || l.parked[1].job.Target != "c" {
+		t.Fatalf("parked = %+v, want the newer b then c", l.parked)
+	}
+
+	// b has waited too long and is dropped; c runs
+	l.parked[0].since = time.Now().Add(-2 * time.Minute)
+	release()
+	job, release, ok := l.Next()
+	if !ok || job.Target != "c" {
+		t.Fatalf("Next = %q, %v; want c", job.Target, ok)
+	}
+	release()
+	if _, _, ok := l.Next(); ok || len(l.parked) != 0 {
+		t.Fatalf("parked = %+v after expiry, want none", l.parked)
+	}
+}
+
+func TestLimiterGroups(t *testing.T) {
+	l := NewLimiter(LimitOptions{
+		PerBastion: 1,
+		Label:      "site", PerLabel: 2,
+		SubnetBits: 24, PerSubnet: 3,
+		DefaultPort: 2200,
+	})
+	job := Job{
+		Host:   "10.1.2.3",
+		Labels: map[string]string{"site": "ams"},
+		Jump:   []Hop{{Host: "bastion"}, {Host: "bastion", Port: 2200}},
+	}
+
+	got := l.groups(job)
+	want := []limitGroup{
+		{"bastion bastion:2200", 1},
+		{"label site=ams", 2},
+		{"subnet 10.1.2.0/24", 3},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("groups = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("groups[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestLimiterNil(t *testing.T) {
+	var l *Limiter
+	release, ok := l.Acquire(viaBastion("a", 0))
+	if !ok {
+		t.Fatal("nil Limiter did not admit")
+	}
+	release()
+	if _, _, ok := l.Next(); ok {
+		t.Fatal("nil Limiter returned a parked job")
+	}
+}
@@ -12,7 +12,8 @@ type Scheduler struct {
 	interval time.Duration
 	jitter   time.Duration
 
-	jobCh chan Job
+	jobCh   chan Job
+	breaker *Breaker // nil = never skip
 
 	// stats (atomic) for observability
 	enqueued uint64
@@ -23,11 +24,13 @@ type Options struct {
 	Interval time.Duration
 	Jitter   time.Duration
 	JobCh    chan Job
+	Breaker  *Breaker
 }
 
 // NewScheduler creates a scheduler that periodically enqueues jobs into jobCh.
 // - Interval: base schedule interval
 // - Jitter: random delay added each cycle (0..Jitter) to reduce herd effects
+// - Breaker: optional; targets in backoff are not enqueued
 func NewScheduler(opts Options) *Scheduler {
 	if opts.Interval <= 0 {
 		opts.Interval = 10 * time.Second
@@ -36,6 +39,7 @@ func NewScheduler(opts Options) *Scheduler {
 		interval: opts.Interval,
 		jitter:   opts.Jitter,
 		jobCh:    opts.JobCh,
+		breaker:  opts.Breaker,
 	}
 }
 
@@ -78,6 +82,10 @@ func (s *Scheduler) enqueueAll(ctx context.Context, jobs []Job) {
 		default:
 		}
 
+		if s.breaker != nil && !s.breaker.Allow(j.Target) {
+			continue
+		}
+
 		select {
 		case s.jobCh <- j:
 			atomic.AddUint64(&s.enqueued, 1)
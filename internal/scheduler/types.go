@@ -1,14 +1,54 @@
 package scheduler
 
+import (
+	"github.com/tastythames/ssh-exporter/internal/collector"
+	"github.com/tastythames/ssh-exporter/internal/sshclient"
+)
+
 type Job struct {
-	Target string
+	Target string // inventory address; cache key + target label
+	Host   string // host to dial (Target without port)
+	Port   int    // 0 = SSH_PORT
 	Labels map[string]string
+	Mode   string // inventory mode: "ssh" | "sftp"
+
+	Collectors []collector.Collector // configured at inventory load; empty = collector.Default
 
 	SSHUser string
+	Auth    Auth
+
+	Jump []Hop // bastions in connect order
 
-	AuthMode     string // "password_env" | "password_file"
+	KnownHostsFiles []string // per-target known_hosts (ssh_config); empty = global policy
+	Algorithms      sshclient.Algorithms
+}
+
+// Auth mirrors inventory.SSHAuth; secrets are resolved by the worker per job.
+type Auth struct {
+	Mode         string // "password_env" | "password_file" | "key" | "cert" | "agent"
 	PasswordEnv  string // e.g. SSH_PASS_ECS1
 	PasswordFile string // e.g. /run/secrets/ecs-1.pass
 
-	KeyPath string // future
+	KeyPath  string // private key for mode=key|cert
+	CertPath string // user certificate for mode=cert
+
+	KeyPassphraseEnv  string // optional, e.g. SSH_KEY_PASS_ECS1
+	KeyPassphraseFile string // optional, e.g. /run/secrets/ecs-1.key.pass
+
+	AgentSocket string // optional, defaults to SSH_AUTH_SOCK
+
+	TOTPSeedEnv    string // optional base32 TOTP seed, e.g. SSH_TOTP_BASTION
+	TOTPSeedFile   string // optional, e.g. /run/secrets/bastion.totp
+	PasswordPrompt string // optional keyboard-interactive prompt rules (regexps)
+	TOTPPrompt     string
+}
+
+type Hop struct {
+	Host string
+	Port int // 0 = SSH_PORT
+	User string
+	Auth Auth
+
+	KnownHostsFiles []string
+	Algorithms      sshclient.Algorithms
 }
@@ -2,124 +2,342 @@ package scheduler
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"os"
-	"strconv"
 	"strings"
 	"time"
 
 	"github.com/tastythames/ssh-exporter/internal/cache"
-	"github.com/tastythames/ssh-exporter/internal/metrics"
+	"github.com/tastythames/ssh-exporter/internal/collector"
 	"github.com/tastythames/ssh-exporter/internal/sshclient"
 )
 
-func StartWorker(id int, jobs <-chan Job, c cache.Cache) {
-	log.Printf("worker %d started", id)
+// WorkerOptions is shared by all workers.
+// - Cache: where results go
+// - Client: shared SSH client (connection pool)
+// - Breaker: optional; fed with every result (only connection failures count)
+// - Limiter: optional; concurrency caps per bastion / label / subnet
+type WorkerOptions struct {
+	Cache   cache.Cache
+	Client  *sshclient.Client
+	Breaker *Breaker
+	Limiter *Limiter
+}
 
-	cfg := sshclient.LoadConfig()
-	cli, err := sshclient.New(cfg)
-	if err != nil {
-		log.Printf("worker %d: ssh client init error: %v", id, err)
-		return
-	}
+// StartWorker consumes jobs until the channel is closed.
+// A job the Limiter cannot admit yet is parked there instead of holding the
+// worker; after each scrape the worker runs whatever parked jobs its freed
+// slots unblocked.
+func StartWorker(id int, jobs <-chan Job, opts WorkerOptions) {
+	log.Printf("worker %d started", id)
 
+	cfg := opts.Client.Config()
 	for job := range jobs {
-		runOneJob(id, cfg, cli, job, c)
+		release, ok := opts.Limiter.Acquire(job)
+		for ok {
+			res := runOneJob(id, cfg, opts.Client, job)
+			release()
+			if opts.Breaker != nil {
+				var berr error
+				if tripsBreaker(res.ErrReason) {
+					berr = res.Err
+				}
+				failures, backoff := opts.Breaker.Record(job.Target, berr)
+				res.ConsecutiveFailures = failures
+				res.Backoff = backoff
+			}
+			opts.Cache.Set(job.Target, res)
+
+			job, release, ok = opts.Limiter.Next()
+		}
 	}
 }
 
-func runOneJob(id int, cfg sshclient.Config, cli *sshclient.Client, job Job, c cache.Cache) {
-	host := strings.TrimSpace(job.Target)
+func runOneJob(id int, cfg sshclient.Config, cli *sshclient.Client, job Job) cache.Result {
+	host := strings.TrimSpace(job.Host)
+	if host == "" {
+		host = strings.TrimSpace(job.Target)
+	}
 	start := time.Now()
 
 	res := cache.Result{
-		At:     time.Now(),
+		At:     start,
 		Labels: job.Labels,
-		Values: map[string]float64{
-			metrics.MetricScrapeDuration: 0,
-			metrics.MetricLastScrapeTs:   0,
-			metrics.MetricTargetUp:       0,
-		},
 	}
 
 	// defaults
-	authMode := strings.TrimSpace(job.AuthMode)
-	if authMode == "" {
-		authMode = "password_env"
-	}
 	user := strings.TrimSpace(job.SSHUser)
 	if user == "" {
 		user = "root" // lab default
 	}
 
-	// pick password
-	password, perr := resolvePassword(authMode, job)
-	if perr != nil {
-		res.Err = perr
+	// pick credentials (target + every jump hop)
+	target, terr := resolveTarget(host, user, job)
+	if terr != nil {
+		setErr(&res, terr)
 		finalizeResult(&res, start)
-		c.Set(job.Target, res)
-		return
+		return res
+	}
+
+	if target.Auth.Mode == "cert" {
+		if exp, err := sshclient.CertExpiry(target.Auth.CertPath); err == nil && !exp.IsZero() {
+			res.UserCertExpiry = exp
+		}
 	}
 
 	// ctx per job (cancel immediately at end of this function)
 	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
 	defer cancel()
 
-	log.Printf("worker %d got job: target=%s labels=%v auth=%s", id, job.Target, job.Labels, authMode)
+	log.Printf("worker %d got job: target=%s labels=%v auth=%s jump=%d", id, job.Target, job.Labels, job.Auth.Mode, len(job.Jump))
+
+	cols := job.Collectors
+	if len(cols) == 0 {
+		var cerr error
+		if cols, cerr = collector.Lookup(nil, nil); cerr != nil {
+			setErr(&res, cerr)
+			finalizeResult(&res, start)
+			return res
+		}
+	}
+
+	// one command per distinct kind; collectors may share a command
+	var cmds []sshclient.AllowedCommand
+	cmdIdx := make(map[string]int)
+	for _, col := range cols {
+		kind := col.Command().Kind()
+		if _, ok := cmdIdx[kind]; !ok {
+			cmdIdx[kind] = len(cmds)
+			cmds = append(cmds, col.Command())
+		}
+	}
 
-	out, e := cli.RunPassword(ctx, host, user, password, "cat /proc/uptime")
+	trace := &sshclient.Trace{}
+	outs, e := cli.RunBatch(sshclient.WithTrace(ctx, trace), target, cmds)
 	finalizeResult(&res, start)
+	res.Phases = trace.Seconds()
+
+	if hk, ok := cli.HostKeyStatus(target); ok {
+		res.HostKey = &cache.HostKey{
+			Type:            hk.KeyType,
+			Fingerprint:     hk.Fingerprint,
+			Changed:         hk.Changed,
+			CertValidBefore: hk.CertValidBefore,
+		}
+	}
+	if n, ok := cli.Negotiated(target); ok {
+		res.Crypto = &cache.Crypto{
+			KeyExchange:        n.KeyExchange,
+			HostKey:            n.HostKey,
+			CipherClientServer: n.CipherClientServer,
+			CipherServerClient: n.CipherServerClient,
+			MACClientServer:    n.MACClientServer,
+			MACServerClient:    n.MACServerClient,
+			Weak:               n.Weak(),
+		}
+	}
 
 	if e != nil {
-		res.Err = e
-		res.Values[metrics.MetricTargetUp] = 0
-		c.Set(job.Target, res)
-		return
+		setErr(&res, e)
+		return res
 	}
 
-	secs := parseFirstFloat(out)
-	res.Values[metrics.MetricTargetUp] = 1
-	res.Values["ssh_os_uptime_seconds"] = secs
+	res.ExitCodes = make(map[string]int, len(cols))
+	for _, col := range cols {
+		out := outs[cmdIdx[col.Command().Kind()]]
+		res.ExitCodes[col.Name()] = out.ExitCode
 
-	c.Set(job.Target, res)
+		if out.Truncated {
+			log.Printf("worker %d target=%s collector=%s: command output truncated", id, job.Target, col.Name())
+		}
+		if !out.OK() {
+			if res.Err == nil {
+				setErr(&res, &CommandError{Collector: col.Name(), Output: out})
+			}
+			continue
+		}
+
+		samples, perr := col.Collect(out.Stdout)
+		if perr != nil {
+			if res.Err == nil {
+				setErr(&res, &ParseError{What: col.Name(), Err: perr})
+			}
+			continue
+		}
+		for _, sm := range samples {
+			res.Add(sm.Name, sm.Help, sm.Type, sm.Labels, sm.Value)
+		}
+	}
+	return res
+}
+
+// resolveTarget reads the secrets for the target and its jump hops.
+func resolveTarget(host, user string, job Job) (sshclient.Target, error) {
+	auth, err := resolveAuth(job.Auth)
+	if err != nil {
+		return sshclient.Target{}, err
+	}
+
+	t := sshclient.Target{Endpoint: sshclient.Endpoint{
+		Host: host,
+		Port: job.Port,
+		User: user,
+		Auth: auth,
+
+		KnownHostsFiles: job.KnownHostsFiles,
+		Algorithms:      job.Algorithms,
+	}}
+	if job.Mode == "sftp" {
+		t.Transport = sshclient.TransportSFTP
+	}
+	for _, h := range job.Jump {
+		hauth, err := resolveAuth(h.Auth)
+		if err != nil {
+			return sshclient.Target{}, fmt.Errorf("jump %s: %w", h.Host, err)
+		}
+		t.Jump = append(t.Jump, sshclient.Endpoint{
+			Host: strings.TrimSpace(h.Host),
+			Port: h.Port,
+			User: strings.TrimSpace(h.User),
+			Auth: hauth,
+
+			KnownHostsFiles: h.KnownHostsFiles,
+			Algorithms:      h.Algorithms,
+		})
+	}
+	return t, nil
+}
+
+func resolveAuth(a Auth) (sshclient.Auth, error) {
+	auth, err := resolveBaseAuth(a)
+	if err != nil {
+		return sshclient.Auth{}, err
+	}
+	auth.PasswordPrompt = a.PasswordPrompt
+	auth.TOTPPrompt = a.TOTPPrompt
+
+	seed, err := resolveTOTPSeed(a)
+	if err != nil {
+		return sshclient.Auth{}, err
+	}
+	auth.TOTPSeed = seed
+	return auth, nil
 }
 
-func resolvePassword(authMode string, job Job) (string, error) {
+func resolveBaseAuth(a Auth) (sshclient.Auth, error) {
+	authMode := strings.TrimSpace(a.Mode)
+	if authMode == "" {
+		authMode = "password_env"
+	}
+
+	switch authMode {
+	case "password_env", "password_file":
+		password, err := resolvePassword(authMode, a)
+		if err != nil {
+			return sshclient.Auth{}, err
+		}
+		return sshclient.Auth{Mode: "password", Password: password}, nil
+
+	case "key", "cert":
+		passphrase, err := resolveKeyPassphrase(a)
+		if err != nil {
+			return sshclient.Auth{}, err
+		}
+		return sshclient.Auth{
+			Mode:          authMode,
+			KeyPath:       strings.TrimSpace(a.KeyPath),
+			CertPath:      strings.TrimSpace(a.CertPath),
+			KeyPassphrase: passphrase,
+		}, nil
+
+	case "agent":
+		return sshclient.Auth{Mode: "agent", AgentSocket: strings.TrimSpace(a.AgentSocket)}, nil
+
+	default:
+		return sshclient.Auth{}, &ErrString{S: "unsupported auth mode: " + authMode}
+	}
+}
+
+func resolvePassword(authMode string, a Auth) (string, error) {
 	switch authMode {
 	case "password_env":
-		env := strings.TrimSpace(job.PasswordEnv)
+		env := strings.TrimSpace(a.PasswordEnv)
 		if env == "" {
-			return "", &ErrString{S: "missing ssh.auth.password_env (mode=password_env)"}
-		}
-		pw := strings.TrimSpace(os.Getenv(env))
-		if pw == "" {
-			return "", &ErrString{S: "empty env var: " + env}
+			return "", &SecretError{S: "missing ssh.auth.password_env (mode=password_env)"}
 		}
-		return pw, nil
+		return readSecretEnv(env)
 
 	case "password_file":
-		p := strings.TrimSpace(job.PasswordFile)
+		p := strings.TrimSpace(a.PasswordFile)
 		if p == "" {
-			return "", &ErrString{S: "missing ssh.auth.password_file (mode=password_file)"}
-		}
-		b, err := os.ReadFile(p)
-		if err != nil {
-			return "", err
-		}
-		pw := strings.TrimSpace(string(b))
-		if pw == "" {
-			return "", &ErrString{S: "empty password_file: " + p}
+			return "", &SecretError{S: "missing ssh.auth.password_file (mode=password_file)"}
 		}
-		return pw, nil
+		return readSecretFile(p)
 
 	default:
 		return "", &ErrString{S: "unsupported auth mode: " + authMode}
 	}
 }
 
+// resolveKeyPassphrase returns "" when no passphrase source is configured
+// (unencrypted key).
+func resolveKeyPassphrase(a Auth) (string, error) {
+	if env := strings.TrimSpace(a.KeyPassphraseEnv); env != "" {
+		return readSecretEnv(env)
+	}
+	if p := strings.TrimSpace(a.KeyPassphraseFile); p != "" {
+		return readSecretFile(p)
+	}
+	return "", nil
+}
+
+// resolveTOTPSeed returns nil when no TOTP seed is configured.
+func resolveTOTPSeed(a Auth) ([]byte, error) {
+	var (
+		v   string
+		err error
+	)
+	switch {
+	case strings.TrimSpace(a.TOTPSeedEnv) != "":
+		v, err = readSecretEnv(strings.TrimSpace(a.TOTPSeedEnv))
+	case strings.TrimSpace(a.TOTPSeedFile) != "":
+		v, err = readSecretFile(strings.TrimSpace(a.TOTPSeedFile))
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	seed, err := sshclient.DecodeTOTPSeed(v)
+	if err != nil {
+		return nil, &SecretError{S: err.Error()}
+	}
+	return seed, nil
+}
+
+func readSecretEnv(env string) (string, error) {
+	v := strings.TrimSpace(os.Getenv(env))
+	if v == "" {
+		return "", &SecretError{S: "empty env var: " + env}
+	}
+	return v, nil
+}
+
+func readSecretFile(p string) (string, error) {
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return "", &SecretError{S: err.Error()}
+	}
+	v := strings.TrimSpace(string(b))
+	if v == "" {
+		return "", &SecretError{S: "empty secret file: " + p}
+	}
+	return v, nil
+}
+
 func finalizeResult(res *cache.Result, start time.Time) {
-	res.Values[metrics.MetricScrapeDuration] = time.Since(start).Seconds()
-	res.Values[metrics.MetricLastScrapeTs] = float64(time.Now().Unix())
+	res.Duration = time.Since(start)
 }
 
 // ---- tiny helpers ----
@@ -128,14 +346,33 @@ type ErrString struct{ S string }
 
 func (e *ErrString) Error() string { return e.S }
 
-func parseFirstFloat(s string) float64 {
-	fields := strings.Fields(s)
-	if len(fields) == 0 {
-		return 0
+// SecretError is a credential that could not be read (env var, file).
+type SecretError struct{ S string }
+
+func (e *SecretError) Error() string { return e.S }
+
+// CommandError is a remote command that ran but did not exit cleanly.
+type CommandError struct {
+	Collector string
+	Output    sshclient.Output
+}
+
+func (e *CommandError) Error() string {
+	msg := fmt.Sprintf("%s: exit status %d", e.Collector, e.Output.ExitCode)
+	if e.Output.Signal != "" {
+		msg += " (signal " + e.Output.Signal + ")"
 	}
-	v, err := strconv.ParseFloat(fields[0], 64)
-	if err != nil {
-		return 0
+	if ln, _, _ := strings.Cut(strings.TrimSpace(e.Output.Stderr), "\n"); ln != "" {
+		msg += ": " + ln
 	}
-	return v
+	return msg
 }
+
+// ParseError is command output that did not parse.
+type ParseError struct {
+	What string
+	Err  error
+}
+
+func (e *ParseError) Error() string { return "parse " + e.What + ": " + e.Err.Error() }
+func (e *ParseError) Unwrap() error { return e.Err }
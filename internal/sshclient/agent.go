@@ -0,0 +1,32 @@
+package sshclient
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// agentAuth offers the keys of a running ssh-agent.
+// socket overrides SSH_AUTH_SOCK when non-empty.
+func agentAuth(ctx context.Context, socket string) (ssh.AuthMethod, io.Closer, error) {
+	if socket == "" {
+		socket = os.Getenv("SSH_AUTH_SOCK")
+	}
+	if socket == "" {
+		return nil, nil, fmt.Errorf("ssh agent socket is empty (set SSH_AUTH_SOCK or ssh.auth.agent_socket)")
+	}
+
+	dialer := net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "unix", socket)
+	if err != nil {
+		return nil, nil, fmt.Errorf("connect ssh agent: %w", err)
+	}
+
+	ag := agent.NewClient(conn)
+	return ssh.PublicKeysCallback(ag.Signers), conn, nil
+}
@@ -0,0 +1,168 @@
+package sshclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// serveAgent runs an in-process ssh-agent holding one ed25519 key on a
+// unix socket in a temp dir and returns the socket path and the public key.
+func serveAgent(t *testing.T) (string, ssh.PublicKey) {
+	t.Helper()
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyring := agent.NewKeyring()
+	if err := keyring.Add(agent.AddedKey{PrivateKey: priv}); err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sock := filepath.Join(t.TempDir(), "agent.sock")
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				_ = agent.ServeAgent(keyring, c)
+			}()
+		}
+	}()
+	return sock, signer.PublicKey()
+}
+
+// handshake runs an SSH handshake over loopback TCP against a server that
+// only accepts allowed, and returns the client side error.
+func handshake(t *testing.T, auth []ssh.AuthMethod, allowed ssh.PublicKey) error {
+	t.Helper()
+
+	_, hostPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostSigner, err := ssh.NewSignerFromKey(hostPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srvCfg := &ssh.ServerConfig{
+		PublicKeyCallback: func(_ ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if bytes.Equal(key.Marshal(), allowed.Marshal()) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("unknown key")
+		},
+	}
+	srvCfg.AddHostKey(hostSigner)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		sc, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer sc.Close()
+		conn, _, _, err := ssh.NewServerConn(sc, srvCfg)
+		if err == nil {
+			_ = conn.Close()
+		}
+	}()
+
+	cc, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cc.Close()
+	conn, _, _, err := ssh.NewClientConn(cc, l.Addr().String(), &ssh.ClientConfig{
+		User:            "monitor",
+		Auth:            auth,
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	})
+	if err == nil {
+		_ = conn.Close()
+	}
+	return err
+}
+
+func TestAgentAuth(t *testing.T) {
+	sock, pub := serveAgent(t)
+
+	methods, closer, err := Auth{Mode: "agent", AgentSocket: sock}.methods(context.Background())
+	if err != nil {
+		t.Fatalf("methods: %v", err)
+	}
+	if closer == nil {
+		t.Fatal("methods returned no closer for the agent connection")
+	}
+	defer closer.Close()
+
+	if err := handshake(t, methods, pub); err != nil {
+		t.Fatalf("handshake with agent key: %v", err)
+	}
+
+	// a server that does not know the agent's key must reject it
+	_, other, _ := ed25519.GenerateKey(rand.Reader)
+	otherSigner, _ := ssh.NewSignerFromKey(other)
+	if err := handshake(t, methods, otherSigner.PublicKey()); err == nil {
+		t.Fatal("handshake succeeded with a key the server does not accept")
+	}
+}
+
+func TestAgentAuthEnvFallback(t *testing.T) {
+	sock, pub := serveAgent(t)
+	t.Setenv("SSH_AUTH_SOCK", sock)
+
+	methods, closer, err := Auth{Mode: "agent"}.methods(context.Background())
+	if err != nil {
+		t.Fatalf("methods: %v", err)
+	}
+	defer closer.Close()
+	if err := handshake(t, methods, pub); err != nil {
+		t.Fatalf("handshake via SSH_AUTH_SOCK: %v", err)
+	}
+}
+
+func TestAgentAuthNoSocket(t *testing.T) {
+	t.Setenv("SSH_AUTH_SOCK", "")
+
+	_, _, err := Auth{Mode: "agent"}.methods(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "ssh agent socket is empty") {
+		t.Fatalf("err = %v, want empty socket error", err)
+	}
+}
+
+func TestAgentAuthDialError(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing.sock")
+
+	_, _, err := Auth{Mode: "agent", AgentSocket: missing}.methods(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "connect ssh agent") {
+		t.Fatalf("err = %v, want connect error", err)
+	}
+}
@@ -0,0 +1,120 @@
+package sshclient
+
+import (
+	"fmt"
+	"slices"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Algorithms restricts the crypto offered during the handshake.
+// Empty lists mean the x/crypto defaults.
+type Algorithms struct {
+	KeyExchanges []string
+	Ciphers      []string
+	MACs         []string
+	HostKeys     []string
+}
+
+// Override returns a with every non-empty list of o replacing its own.
+func (a Algorithms) Override(o Algorithms) Algorithms {
+	if len(o.KeyExchanges) > 0 {
+		a.KeyExchanges = o.KeyExchanges
+	}
+	if len(o.Ciphers) > 0 {
+		a.Ciphers = o.Ciphers
+	}
+	if len(o.MACs) > 0 {
+		a.MACs = o.MACs
+	}
+	if len(o.HostKeys) > 0 {
+		a.HostKeys = o.HostKeys
+	}
+	return a
+}
+
+// Validate rejects names x/crypto does not implement (typos would otherwise
+// only show up as "no common algorithm" at scrape time).
+func (a Algorithms) Validate() error {
+	sup := ssh.SupportedAlgorithms()
+	ins := ssh.InsecureAlgorithms()
+	check := func(what string, names, supported, insecure []string) error {
+		for _, n := range names {
+			if !slices.Contains(supported, n) && !slices.Contains(insecure, n) {
+				return fmt.Errorf("unsupported %s algorithm %q", what, n)
+			}
+		}
+		return nil
+	}
+
+	if err := check("kex", a.KeyExchanges, sup.KeyExchanges, ins.KeyExchanges); err != nil {
+		return err
+	}
+	if err := check("cipher", a.Ciphers, sup.Ciphers, ins.Ciphers); err != nil {
+		return err
+	}
+	if err := check("mac", a.MACs, sup.MACs, ins.MACs); err != nil {
+		return err
+	}
+	return check("host key", a.HostKeys, sup.HostKeys, ins.HostKeys)
+}
+
+// Negotiated is what a handshake agreed on.
+type Negotiated struct {
+	KeyExchange string
+	HostKey     string
+
+	CipherClientServer string
+	CipherServerClient string
+	MACClientServer    string // empty for AEAD ciphers
+	MACServerClient    string
+}
+
+// Weak reports whether any negotiated algorithm is one x/crypto lists as insecure.
+func (n Negotiated) Weak() bool {
+	ins := ssh.InsecureAlgorithms()
+	return slices.Contains(ins.KeyExchanges, n.KeyExchange) ||
+		slices.Contains(ins.HostKeys, n.HostKey) ||
+		slices.Contains(ins.Ciphers, n.CipherClientServer) ||
+		slices.Contains(ins.Ciphers, n.CipherServerClient) ||
+		slices.Contains(ins.MACs, n.MACClientServer) ||
+		slices.Contains(ins.MACs, n.MACServerClient)
+}
+
+func negotiatedFrom(conn ssh.Conn) (Negotiated, bool) {
+	am, ok := conn.(ssh.AlgorithmsConnMetadata)
+	if !ok {
+		return Negotiated{}, false
+	}
+	a := am.Algorithms()
+	return Negotiated{
+		KeyExchange:        a.KeyExchange,
+		HostKey:            a.HostKey,
+		CipherClientServer: a.Write.Cipher,
+		CipherServerClient: a.Read.Cipher,
+		MACClientServer:    a.Write.MAC,
+		MACServerClient:    a.Read.MAC,
+	}, true
+}
+
+// hostKeyAlgorithms combines the algorithms we can verify for a host
+// (trusted, nil = any) with the configured allowlist.
+func hostKeyAlgorithms(trusted, allowed []string) []string {
+	switch {
+	case len(allowed) == 0:
+		return trusted
+	case trusted == nil:
+		return allowed
+	}
+	var out []string
+	for _, a := range trusted {
+		if slices.Contains(allowed, a) {
+			out = append(out, a)
+		}
+	}
+	if len(out) == 0 {
+		// nothing trusted is allowed; let the handshake fail on the allowlist
+		return allowed
+	}
+	return out
+}
@@ -0,0 +1,150 @@
+package sshclient
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Auth holds resolved credentials for one SSH hop (secrets already read).
+type Auth struct {
+	Mode string // "password" | "key" | "cert" | "agent"
+
+	Password string
+
+	KeyPath       string // mode=key|cert
+	KeyPassphrase string // optional, for encrypted keys
+	CertPath      string // mode=cert
+
+	AgentSocket string // mode=agent, defaults to SSH_AUTH_SOCK
+
+	// keyboard-interactive second factor (any mode); nil = no TOTP
+	TOTPSeed []byte
+
+	// regexps deciding which keyboard-interactive prompt gets what;
+	// "" = DefaultPasswordPrompt / DefaultTOTPPrompt
+	PasswordPrompt string
+	TOTPPrompt     string
+}
+
+// Default keyboard-interactive prompt rules. The TOTP rule is checked first,
+// so "One-time password:" gets a code.
+const (
+	DefaultPasswordPrompt = `(?i)pass(word|phrase|code)?`
+	DefaultTOTPPrompt     = `(?i)(verification|one[- ]time|otp|token|authenticator|2fa|mfa|\bcode\b)`
+)
+
+// methods builds ssh auth methods for a. The returned closer (may be nil)
+// must be closed once the handshake is done (agent connection).
+func (a Auth) methods(ctx context.Context) ([]ssh.AuthMethod, io.Closer, error) {
+	switch a.Mode {
+	case "password":
+		if a.Password == "" {
+			return nil, nil, fmt.Errorf("ssh password is empty")
+		}
+		ki, err := a.keyboardInteractive()
+		if err != nil {
+			return nil, nil, err
+		}
+		return []ssh.AuthMethod{ssh.Password(a.Password), ki}, nil, nil
+
+	case "key":
+		signer, err := LoadPrivateKey(a.KeyPath, a.KeyPassphrase)
+		if err != nil {
+			return nil, nil, err
+		}
+		return a.withTOTP(ssh.PublicKeys(signer))
+
+	case "cert":
+		signer, err := LoadCertSigner(a.CertPath, a.KeyPath, a.KeyPassphrase)
+		if err != nil {
+			return nil, nil, err
+		}
+		return a.withTOTP(ssh.PublicKeys(signer))
+
+	case "agent":
+		m, closer, err := agentAuth(ctx, a.AgentSocket)
+		if err != nil {
+			return nil, nil, err
+		}
+		methods, _, err := a.withTOTP(m)
+		if err != nil {
+			_ = closer.Close()
+			return nil, nil, err
+		}
+		return methods, closer, nil
+
+	default:
+		return nil, nil, fmt.Errorf("unsupported auth mode: %q", a.Mode)
+	}
+}
+
+// withTOTP adds keyboard-interactive after m when a TOTP seed is set
+// (servers with "AuthenticationMethods publickey,keyboard-interactive").
+func (a Auth) withTOTP(m ssh.AuthMethod) ([]ssh.AuthMethod, io.Closer, error) {
+	if a.TOTPSeed == nil {
+		return []ssh.AuthMethod{m}, nil, nil
+	}
+	ki, err := a.keyboardInteractive()
+	if err != nil {
+		return nil, nil, err
+	}
+	return []ssh.AuthMethod{m, ki}, nil, nil
+}
+
+// keyboardInteractive answers prompts by rule: TOTP prompts get a fresh
+// code, password prompts the password. Without TOTP or a custom password
+// rule every prompt gets the password (plain PAM password hosts).
+func (a Auth) keyboardInteractive() (ssh.AuthMethod, error) {
+	passPat := a.PasswordPrompt
+	if passPat == "" {
+		passPat = DefaultPasswordPrompt
+	}
+	passRe, err := regexp.Compile(passPat)
+	if err != nil {
+		return nil, fmt.Errorf("password prompt rule: %w", err)
+	}
+	totpPat := a.TOTPPrompt
+	if totpPat == "" {
+		totpPat = DefaultTOTPPrompt
+	}
+	totpRe, err := regexp.Compile(totpPat)
+	if err != nil {
+		return nil, fmt.Errorf("totp prompt rule: %w", err)
+	}
+	strict := a.TOTPSeed != nil || a.PasswordPrompt != ""
+
+	return ssh.KeyboardInteractive(func(_user, _instruction string, questions []string, _echos []bool) ([]string, error) {
+		answers := make([]string, len(questions))
+		for i, q := range questions {
+			switch {
+			case a.TOTPSeed != nil && totpRe.MatchString(q):
+				answers[i] = TOTP(a.TOTPSeed, time.Now())
+			case a.Password != "" && (!strict || passRe.MatchString(q)):
+				answers[i] = a.Password
+			default:
+				return nil, fmt.Errorf("no answer for keyboard-interactive prompt %q", q)
+			}
+		}
+		return answers, nil
+	}), nil
+}
+
+// key identifies the credential (not the secret itself) for connection sharing.
+func (a Auth) key() string {
+	k := a.Mode
+	switch a.Mode {
+	case "key", "cert":
+		k += ":" + a.KeyPath + ":" + a.CertPath
+	case "agent":
+		k += ":" + a.AgentSocket
+	}
+	if a.TOTPSeed != nil {
+		k += "+totp"
+	}
+	return k
+}
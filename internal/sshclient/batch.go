@@ -0,0 +1,146 @@
+package sshclient
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// RunBatch executes cmds on t and returns one Output per command, in order.
+// With batching enabled (the default) all commands share one session: a
+// /bin/sh script on stdin runs them in turn and prints a delimiter line with
+// a random token plus the exit status around each section, on stdout and
+// stderr both. Otherwise every command gets its own session via Run.
+//
+// err is a failure of the whole batch (transport, auth, timeout). A command
+// whose section is missing (output cap hit, script killed) reports
+// ExitCode -1 and Truncated.
+//
+// SFTP targets always read every file over one session.
+func (c *Client) RunBatch(ctx context.Context, t Target, cmds []AllowedCommand) ([]Output, error) {
+	if t.Transport == TransportSFTP {
+		return c.runSFTP(ctx, t, cmds)
+	}
+	if c.cfg.BatchDisabled || len(cmds) == 1 {
+		outs := make([]Output, 0, len(cmds))
+		for _, cmd := range cmds {
+			out, err := c.Run(ctx, t, cmd)
+			if err != nil {
+				return nil, err
+			}
+			outs = append(outs, out)
+		}
+		return outs, nil
+	}
+
+	cmdlines := make([]string, len(cmds))
+	for i, cmd := range cmds {
+		cmdline, ok := cmd.cmdline()
+		if !ok {
+			err := ErrUnsupported(cmd)
+			c.audit(t, cmd, "", Output{}, err)
+			return nil, err
+		}
+		cmdlines[i] = cmdline
+	}
+
+	token, err := batchToken()
+	if err != nil {
+		return nil, err
+	}
+	script := batchScript(token, cmdlines)
+
+	raw, err := c.exec(ctx, t, "/bin/sh -s", strings.NewReader(script), c.maxOutput()*len(cmds))
+	if err == nil && !raw.OK() {
+		err = fmt.Errorf("batch script: exit status %d%s", raw.ExitCode, signalSuffix(raw.Signal))
+	}
+	if err != nil {
+		for i, cmd := range cmds {
+			c.audit(t, cmd, cmdlines[i], Output{}, err)
+		}
+		return nil, err
+	}
+
+	outs := splitBatch(token, raw, len(cmds))
+	for i, cmd := range cmds {
+		c.audit(t, cmd, cmdlines[i], outs[i], nil)
+	}
+	return outs, nil
+}
+
+func batchToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("batch token: %w", err)
+	}
+	return "--ssh-exporter-" + hex.EncodeToString(b), nil
+}
+
+// batchScript wraps each command as:
+//
+//	<token> begin <i>
+//	...output...
+//	<token> end <i> <exit status>
+//
+// The newline before an end line is ours and is stripped again by splitBatch.
+// Commands run in a subshell reading /dev/null: stdin is the script itself.
+func batchScript(token string, cmdlines []string) string {
+	var b strings.Builder
+	for i, cmdline := range cmdlines {
+		fmt.Fprintf(&b, "printf '%%s begin %d\\n' '%s'; printf '%%s begin %d\\n' '%s' >&2\n", i, token, i, token)
+		fmt.Fprintf(&b, "(\n%s\n) </dev/null\n", cmdline)
+		fmt.Fprintf(&b, "rc=$?\n")
+		fmt.Fprintf(&b, "printf '\\n%%s end %d %%d\\n' '%s' \"$rc\"; printf '\\n%%s end %d %%d\\n' '%s' \"$rc\" >&2\n", i, token, i, token)
+	}
+	b.WriteString("exit 0\n")
+	return b.String()
+}
+
+func splitBatch(token string, raw Output, n int) []Output {
+	outs := make([]Output, n)
+	for i := range outs {
+		stdout, code, okOut := batchSection(raw.Stdout, token, i)
+		stderr, _, okErr := batchSection(raw.Stderr, token, i)
+		outs[i] = Output{Stdout: stdout, Stderr: stderr, ExitCode: code}
+		if !okOut || !okErr {
+			outs[i].ExitCode = -1
+			outs[i].Truncated = true
+		}
+	}
+	return outs
+}
+
+// batchSection returns the output of section i and its exit status.
+func batchSection(stream, token string, i int) (string, int, bool) {
+	begin := fmt.Sprintf("%s begin %d\n", token, i)
+	end := fmt.Sprintf("\n%s end %d ", token, i)
+
+	b := strings.Index(stream, begin)
+	if b < 0 {
+		return "", -1, false
+	}
+	body := stream[b+len(begin):]
+	e := strings.Index(body, end)
+	if e < 0 {
+		return body, -1, false
+	}
+	rest := body[e+len(end):]
+	body = body[:e]
+
+	ln, _, found := strings.Cut(rest, "\n")
+	code, err := strconv.Atoi(ln)
+	if !found || err != nil {
+		return body, -1, false
+	}
+	return body, code, true
+}
+
+func signalSuffix(sig string) string {
+	if sig == "" {
+		return ""
+	}
+	return " (signal " + sig + ")"
+}
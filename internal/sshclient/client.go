@@ -2,103 +2,329 @@ package sshclient
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"log"
 	"net"
+	"strings"
+	"sync"
 	"time"
 
 	"golang.org/x/crypto/ssh"
 )
 
+// ErrAuthFailed is returned when the server rejected every auth method.
+var ErrAuthFailed = errors.New("ssh auth failed")
+
 type Client struct {
-	cfg Config
-}
+	cfg      Config
+	hostKeys *hostKeyVerifier
+	pool     *pool
 
-func New(cfg Config) (*Client, error) {
-	return &Client{cfg: cfg}, nil
+	// negotiated crypto per host:port, for the info metric
+	mu         sync.Mutex
+	negotiated map[string]Negotiated
 }
 
-// RunPassword executes cmd on host using username/password (per-target).
-func (c *Client) RunPassword(ctx context.Context, host, user, password, cmd string) (string, error) {
-	if user == "" {
-		return "", fmt.Errorf("ssh user is empty")
+func New(cfg Config) (*Client, error) {
+	if err := cfg.Algorithms.Validate(); err != nil {
+		return nil, err
 	}
-	if password == "" {
-		return "", fmt.Errorf("ssh password is empty")
+	hk, err := newHostKeyVerifier(cfg)
+	if err != nil {
+		return nil, err
 	}
+	return &Client{
+		cfg:      cfg,
+		hostKeys: hk,
+		pool:     newPool(),
 
-	addr := net.JoinHostPort(host, fmt.Sprintf("%d", c.cfg.Port))
+		negotiated: make(map[string]Negotiated),
+	}, nil
+}
 
-	// HostKey policy (lab-first)
-	// TODO: harden later with known_hosts
-	hk := ssh.InsecureIgnoreHostKey()
-	_ = c.cfg.InsecureSkipHostKey // (kept for future: enforce known_hosts when false)
+func (c *Client) Config() Config { return c.cfg }
 
-	sshCfg := &ssh.ClientConfig{
-		User:            user,
-		HostKeyCallback: hk,
-		Timeout:         c.cfg.Timeout,
-		Auth: []ssh.AuthMethod{
-			ssh.Password(password),
-			ssh.KeyboardInteractive(func(_user, _instruction string, questions []string, _echos []bool) ([]string, error) {
-				answers := make([]string, len(questions))
-				for i := range questions {
-					answers[i] = password
-				}
-				return answers, nil
-			}),
-		},
-	}
+// HostKeyStatus returns the last host key check result for t.
+func (c *Client) HostKeyStatus(t Target) (HostKeyStatus, bool) {
+	return c.hostKeys.Status(c.hostID(t.Jump, t.Endpoint))
+}
 
-	// Dial with context so it won't hang forever.
-	dialer := net.Dialer{}
-	conn, err := dialer.DialContext(ctx, "tcp", addr)
-	if err != nil {
-		return "", err
+// Negotiated returns the crypto agreed on the last handshake with t.
+func (c *Client) Negotiated(t Target) (Negotiated, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n, ok := c.negotiated[c.hostID(t.Jump, t.Endpoint)]
+	return n, ok
+}
+
+// Run executes a registered command on t (through its jump chain, if any).
+// err is only set when the command could not be run or did not finish
+// (transport, auth, timeout); a non-zero exit is reported in Output.
+// Every attempt is written to the audit log.
+func (c *Client) Run(ctx context.Context, t Target, cmd AllowedCommand) (Output, error) {
+	if t.Transport == TransportSFTP {
+		outs, err := c.runSFTP(ctx, t, []AllowedCommand{cmd})
+		if err != nil {
+			return Output{}, err
+		}
+		return outs[0], nil
 	}
-	defer conn.Close()
 
-	// Make sure the underlying TCP conn obeys ctx timeout too.
-	// (ssh handshake can still hang without deadlines)
-	if deadline, ok := ctx.Deadline(); ok {
-		_ = conn.SetDeadline(deadline)
-	} else {
-		_ = conn.SetDeadline(time.Now().Add(c.cfg.Timeout))
+	cmdline, ok := cmd.cmdline()
+	if !ok {
+		err := ErrUnsupported(cmd)
+		c.audit(t, cmd, "", Output{}, err)
+		return Output{}, err
 	}
+	out, err := c.exec(ctx, t, cmdline, nil, c.maxOutput())
+	c.audit(t, cmd, cmdline, out, err)
+	return out, err
+}
 
-	cconn, chans, reqs, err := ssh.NewClientConn(conn, addr, sshCfg)
+// audit logs one command execution: who, where, what and how it ended.
+func (c *Client) audit(t Target, cmd AllowedCommand, cmdline string, out Output, err error) {
+	hops := make([]string, 0, len(t.Jump))
+	for _, j := range t.Jump {
+		hops = append(hops, j.User+"@"+c.addr(j))
+	}
+	status := fmt.Sprintf("exit=%d", out.ExitCode)
+	if out.Signal != "" {
+		status += " signal=" + out.Signal
+	}
 	if err != nil {
-		return "", err
+		status = fmt.Sprintf("error=%q", err.Error())
+	}
+	log.Printf("audit: target=%s@%s jump=%v kind=%s cmd=%q %s",
+		t.User, c.addr(t.Endpoint), hops, cmd.kind, cmdline, status)
+}
+
+func (c *Client) maxOutput() int {
+	if c.cfg.MaxOutputBytes <= 0 {
+		return DefaultMaxOutputBytes
 	}
-	client := ssh.NewClient(cconn, chans, reqs)
-	defer client.Close()
+	return c.cfg.MaxOutputBytes
+}
 
-	sess, err := client.NewSession()
+// exec runs cmd in a new session; stdin may be nil. max caps stdout and
+// stderr each.
+func (c *Client) exec(ctx context.Context, t Target, cmd string, stdin io.Reader, max int) (Output, error) {
+	sess, release, err := c.session(ctx, t)
 	if err != nil {
-		return "", err
+		return Output{}, err
 	}
+	defer release()
 	defer sess.Close()
 
+	stdout := &cappedBuffer{max: max}
+	stderr := &cappedBuffer{max: max}
+	sess.Stdin = stdin
+	sess.Stdout = stdout
+	sess.Stderr = stderr
+
+	done := make(chan error, 1)
+
+	started := time.Now()
+	go func() { done <- sess.Run(cmd) }()
+	defer func() { traceFrom(ctx).add(PhaseCommand, time.Since(started)) }()
+
+	select {
+	case <-ctx.Done():
+		// Best-effort terminate session.
+		_ = sess.Signal(ssh.SIGKILL)
+		return Output{}, ctx.Err()
+	case err := <-done:
+		out := Output{
+			Stdout:    stdout.buf.String(),
+			Stderr:    stderr.buf.String(),
+			Truncated: stdout.truncated || stderr.truncated,
+		}
+		if !out.exitStatus(err) {
+			return out, err
+		}
+		return out, nil
+	}
+}
+
+// session opens a new session on t. Pooled connections are reused; a pooled
+// connection that cannot open a session is dropped and dialed again once.
+// release must be called after the session is closed.
+func (c *Client) session(ctx context.Context, t Target) (*ssh.Session, func(), error) {
+	chain := append(append([]Endpoint(nil), t.Jump...), t.Endpoint)
+	tr := traceFrom(ctx)
+
+	if c.cfg.PoolDisabled {
+		var via *ssh.Client
+		if len(t.Jump) > 0 {
+			v, _, err := c.pooled(ctx, t.Jump, false)
+			if err != nil {
+				return nil, nil, err
+			}
+			via = v
+		}
+		client, err := c.connect(ctx, via, t.Jump, t.Endpoint, false)
+		if err != nil {
+			return nil, nil, err
+		}
+		opened := time.Now()
+		sess, err := newSession(ctx, client, func() { _ = client.Close() })
+		tr.add(PhaseSession, time.Since(opened))
+		if err != nil {
+			_ = client.Close()
+			return nil, nil, err
+		}
+		return sess, func() { _ = client.Close() }, nil
+	}
+
+	for attempt := 0; ; attempt++ {
+		client, key, err := c.pooled(ctx, chain, true)
+		if err != nil {
+			return nil, nil, err
+		}
+		opened := time.Now()
+		sess, err := newSession(ctx, client, func() { c.pool.drop(key, client) })
+		tr.add(PhaseSession, time.Since(opened))
+		if err == nil {
+			return sess, func() {}, nil
+		}
+		c.pool.drop(key, client)
+		if attempt > 0 || ctx.Err() != nil {
+			return nil, nil, err
+		}
+	}
+}
+
+// newSession opens a session on client but gives up when ctx is done:
+// pooled connections have no I/O deadline, so a hung server would block
+// NewSession forever. abandon must close client, which releases the
+// pending channel open; a session that still arrives is closed.
+func newSession(ctx context.Context, client *ssh.Client, abandon func()) (*ssh.Session, error) {
 	type result struct {
-		out []byte
-		err error
+		sess *ssh.Session
+		err  error
 	}
 	done := make(chan result, 1)
-
 	go func() {
-		out, err := sess.CombinedOutput(cmd)
-		done <- result{out: out, err: err}
+		sess, err := client.NewSession()
+		done <- result{sess, err}
 	}()
 
 	select {
-	case <-ctx.Done():
-		// Best-effort terminate session.
-		_ = sess.Signal(ssh.SIGKILL)
-		return "", ctx.Err()
 	case r := <-done:
-		if r.err != nil {
-			// return output for debugging context too
-			return string(r.out), r.err
+		return r.sess, r.err
+	case <-ctx.Done():
+		abandon()
+		go func() {
+			if r := <-done; r.sess != nil {
+				_ = r.sess.Close()
+			}
+		}()
+		return nil, ctx.Err()
+	}
+}
+
+// connect dials e (directly, or via a direct-tcpip channel when via is set)
+// and authenticates. route are the hops via leads through (nil when direct).
+// persistent connections keep no I/O deadline after the handshake.
+func (c *Client) connect(ctx context.Context, via *ssh.Client, route []Endpoint, e Endpoint, persistent bool) (*ssh.Client, error) {
+	if e.User == "" {
+		return nil, fmt.Errorf("ssh user is empty")
+	}
+	addr := c.addr(e)
+	id := c.hostID(route, e)
+
+	auth, closer, err := e.Auth.methods(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if closer != nil {
+		defer closer.Close()
+	}
+
+	tr := traceFrom(ctx)
+	dialStart := time.Now()
+
+	// Dial with context so it won't hang forever.
+	var conn net.Conn
+	if via != nil {
+		conn, err = via.DialContext(ctx, "tcp", addr)
+	} else {
+		dialer := net.Dialer{}
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	tr.add(PhaseConnect, time.Since(dialStart))
+	if err != nil {
+		return nil, ctxErr(ctx, err)
+	}
+
+	// Make sure the underlying TCP conn obeys ctx timeout too.
+	// (ssh handshake can still hang without deadlines)
+	// Tunnelled conns have no deadlines, so close them on ctx instead.
+	if via == nil {
+		if deadline, ok := ctx.Deadline(); ok {
+			_ = conn.SetDeadline(deadline)
+		} else {
+			_ = conn.SetDeadline(time.Now().Add(c.cfg.Timeout))
+		}
+	}
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+
+	// the host key is checked at the end of key exchange, before auth starts
+	hsStart := time.Now()
+	kexDone := time.Time{}
+	checkHostKey := c.hostKeys.Callback(e.KnownHostsFiles, id)
+	hostKeyCallback := func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		kexDone = time.Now()
+		return checkHostKey(hostname, remote, key)
+	}
+
+	algos := c.cfg.Algorithms.Override(e.Algorithms)
+	sshCfg := &ssh.ClientConfig{
+		Config: ssh.Config{
+			KeyExchanges: algos.KeyExchanges,
+			Ciphers:      algos.Ciphers,
+			MACs:         algos.MACs,
+		},
+		User:              e.User,
+		HostKeyCallback:   hostKeyCallback,
+		HostKeyAlgorithms: hostKeyAlgorithms(c.hostKeys.Algorithms(e.KnownHostsFiles, addr, id, conn.RemoteAddr()), algos.HostKeys),
+		Timeout:           c.cfg.Timeout,
+		Auth:              auth,
+	}
+
+	cconn, chans, reqs, err := ssh.NewClientConn(conn, addr, sshCfg)
+	if kexDone.IsZero() {
+		tr.add(PhaseKex, time.Since(hsStart))
+	} else {
+		tr.add(PhaseKex, kexDone.Sub(hsStart))
+		tr.add(PhaseAuth, time.Since(kexDone))
+	}
+	if err != nil {
+		_ = conn.Close()
+		if strings.Contains(err.Error(), "unable to authenticate") {
+			err = fmt.Errorf("%w: %w", ErrAuthFailed, err)
 		}
-		return string(r.out), nil
+		return nil, ctxErr(ctx, err)
+	}
+	if n, ok := negotiatedFrom(cconn); ok {
+		c.mu.Lock()
+		c.negotiated[id] = n
+		c.mu.Unlock()
+	}
+	if persistent && via == nil {
+		_ = conn.SetDeadline(time.Time{})
+	}
+	return ssh.NewClient(cconn, chans, reqs), nil
+}
+
+// ctxErr tags err with ctx's error when ctx ended first: closing the conn on
+// ctx surfaces as "use of closed network connection", which says nothing
+// about the timeout that caused it.
+func ctxErr(ctx context.Context, err error) error {
+	if cerr := ctx.Err(); cerr != nil && !errors.Is(err, cerr) {
+		return fmt.Errorf("%w: %w", cerr, err)
 	}
+	return err
 }
@@ -1,25 +1,117 @@
 package sshclient
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ErrCommandNotAllowed is returned by Run for commands missing from the registry.
+var ErrCommandNotAllowed = errors.New("command not allowed")
 
 // AllowedCommand = คำสั่งที่อนุญาตให้รันเท่านั้น
+// Values only come from the registry (Cmd* / RegisterCommand / LookupCommand),
+// so callers cannot hand Run an arbitrary command line.
 type AllowedCommand struct {
 	kind string
 }
 
+// commandSpec is what a kind runs: a shell command line, and for commands
+// that only read a file, the path to read over SFTP instead.
+type commandSpec struct {
+	cmdline string
+	path    string
+}
+
+var (
+	commandsMu sync.RWMutex
+	commands   = map[string]commandSpec{
+		"meminfo": {cmdline: "cat /proc/meminfo", path: "/proc/meminfo"},
+		"loadavg": {cmdline: "cat /proc/loadavg", path: "/proc/loadavg"},
+		"uptime":  {cmdline: "cat /proc/uptime", path: "/proc/uptime"},
+		"netdev":  {cmdline: "cat /proc/net/dev", path: "/proc/net/dev"},
+	}
+)
+
+// RegisterCommand adds a vetted command line under kind. Meant for collector
+// init code; an existing kind cannot be redefined.
+// Such commands cannot run on SFTP-only targets, see RegisterFile.
+func RegisterCommand(kind, cmdline string) (AllowedCommand, error) {
+	if kind == "" || cmdline == "" {
+		return AllowedCommand{}, fmt.Errorf("register command: empty kind or command line")
+	}
+	return register(kind, commandSpec{cmdline: cmdline})
+}
+
+// RegisterFile adds a kind that reads one file: "cat <path>" over a shell,
+// or a plain read over SFTP.
+func RegisterFile(kind, path string) (AllowedCommand, error) {
+	if kind == "" || !safePath(path) {
+		return AllowedCommand{}, fmt.Errorf("register file: invalid kind or path %q", path)
+	}
+	return register(kind, commandSpec{cmdline: "cat " + path, path: path})
+}
+
+func register(kind string, spec commandSpec) (AllowedCommand, error) {
+	commandsMu.Lock()
+	defer commandsMu.Unlock()
+	if cur, ok := commands[kind]; ok {
+		if cur == spec {
+			return AllowedCommand{kind: kind}, nil
+		}
+		return AllowedCommand{}, fmt.Errorf("register command: kind %q already registered", kind)
+	}
+	commands[kind] = spec
+	return AllowedCommand{kind: kind}, nil
+}
+
+// safePath accepts absolute paths that need no shell quoting.
+func safePath(p string) bool {
+	if !strings.HasPrefix(p, "/") || strings.Contains(p, "..") {
+		return false
+	}
+	for _, r := range p {
+		ok := r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' ||
+			r == '/' || r == '.' || r == '_' || r == '-'
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// LookupCommand returns the registered command for kind.
+func LookupCommand(kind string) (AllowedCommand, bool) {
+	_, ok := AllowedCommand{kind: kind}.spec()
+	return AllowedCommand{kind: kind}, ok
+}
+
+func (c AllowedCommand) Kind() string { return c.kind }
+
 func (c AllowedCommand) String() string {
-	switch c.kind {
-	case "meminfo":
-		return "cat /proc/meminfo"
-	case "loadavg":
-		return "cat /proc/loadavg"
-	case "uptime":
-		return "cat /proc/uptime"
-	case "netdev":
-		return "cat /proc/net/dev"
-	default:
-		return "false"
+	if spec, ok := c.spec(); ok {
+		return spec.cmdline
 	}
+	return "false"
+}
+
+func (c AllowedCommand) spec() (commandSpec, bool) {
+	commandsMu.RLock()
+	defer commandsMu.RUnlock()
+	spec, ok := commands[c.kind]
+	return spec, ok
+}
+
+// Path is the file the command reads ("" when it is not file-backed).
+func (c AllowedCommand) Path() string {
+	spec, _ := c.spec()
+	return spec.path
+}
+
+func (c AllowedCommand) cmdline() (string, bool) {
+	spec, ok := c.spec()
+	return spec.cmdline, ok
 }
 
 func CmdMeminfo() AllowedCommand { return AllowedCommand{kind: "meminfo"} }
@@ -28,5 +120,5 @@ func CmdUptime() AllowedCommand  { return AllowedCommand{kind: "uptime"} }
 func CmdNetDev() AllowedCommand  { return AllowedCommand{kind: "netdev"} }
 
 func ErrUnsupported(cmd AllowedCommand) error {
-	return fmt.Errorf("unsupported command kind=%q", cmd.kind)
+	return fmt.Errorf("%w: unsupported command kind=%q", ErrCommandNotAllowed, cmd.kind)
 }
@@ -2,6 +2,7 @@ package sshclient
 
 import (
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -13,6 +14,31 @@ type Config struct {
 
 	// lab-friendly switch; prod ควร false แล้วใช้ known_hosts จริง
 	InsecureSkipHostKey bool
+
+	// OpenSSH known_hosts files (hashed + [host]:port entries supported)
+	KnownHostsFiles []string
+	// KnownHostsFiles is the ~/.ssh/known_hosts fallback (SSH_KNOWN_HOSTS
+	// unset): a missing file then means "nothing trusted" instead of an error
+	KnownHostsDefault bool
+
+	// trust-on-first-use state file; when set, replaces known_hosts checking
+	TOFUFile string
+
+	// CA public keys trusted to sign host certificates
+	HostCAFiles []string
+
+	// connection pool: reuse authenticated connections across scrapes
+	PoolDisabled      bool
+	KeepaliveInterval time.Duration // 0 = no keepalives
+
+	// crypto allowlists (per-target overrides in the inventory)
+	Algorithms Algorithms
+
+	// cap for stdout and stderr of one command; extra output is dropped
+	MaxOutputBytes int
+
+	// run every command of a scrape in its own session instead of one script
+	BatchDisabled bool
 }
 
 func LoadConfig() Config {
@@ -38,9 +64,73 @@ func LoadConfig() Config {
 		}
 	}
 
+	// SSH_KNOWN_HOSTS: comma-separated list, default ~/.ssh/known_hosts
+	var knownHosts []string
+	knownHostsDefault := false
+	if v := os.Getenv("SSH_KNOWN_HOSTS"); v != "" {
+		knownHosts = splitList(v)
+	} else if home, err := os.UserHomeDir(); err == nil {
+		knownHosts = []string{filepath.Join(home, ".ssh", "known_hosts")}
+		knownHostsDefault = true
+	}
+
+	poolDisabled := false
+	if v := os.Getenv("SSH_POOL"); v != "" {
+		v = strings.ToLower(strings.TrimSpace(v))
+		if v == "0" || v == "false" || v == "no" {
+			poolDisabled = true
+		}
+	}
+
+	keepalive := 30 * time.Second
+	if v := os.Getenv("SSH_KEEPALIVE_SECONDS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			keepalive = time.Duration(n) * time.Second
+		}
+	}
+
+	maxOutput := DefaultMaxOutputBytes
+	if v := os.Getenv("SSH_MAX_OUTPUT_BYTES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			maxOutput = n
+		}
+	}
+
+	batchDisabled := false
+	if v := os.Getenv("SSH_BATCH"); v != "" {
+		v = strings.ToLower(strings.TrimSpace(v))
+		if v == "0" || v == "false" || v == "no" {
+			batchDisabled = true
+		}
+	}
+
 	return Config{
 		Timeout:             timeout,
 		Port:                port,
 		InsecureSkipHostKey: insecure,
+		KnownHostsFiles:     knownHosts,
+		KnownHostsDefault:   knownHostsDefault,
+		TOFUFile:            strings.TrimSpace(os.Getenv("SSH_TOFU_FILE")),
+		HostCAFiles:         splitList(os.Getenv("SSH_HOST_CA_KEYS")),
+		PoolDisabled:        poolDisabled,
+		KeepaliveInterval:   keepalive,
+		Algorithms: Algorithms{
+			KeyExchanges: splitList(os.Getenv("SSH_KEX_ALGORITHMS")),
+			Ciphers:      splitList(os.Getenv("SSH_CIPHERS")),
+			MACs:         splitList(os.Getenv("SSH_MACS")),
+			HostKeys:     splitList(os.Getenv("SSH_HOSTKEY_ALGORITHMS")),
+		},
+		MaxOutputBytes: maxOutput,
+		BatchDisabled:  batchDisabled,
+	}
+}
+
+func splitList(v string) []string {
+	var out []string
+	for _, p := range strings.Split(v, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
 	}
+	return out
 }
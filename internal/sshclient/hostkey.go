@@ -0,0 +1,335 @@
+package sshclient
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// Host key verification errors (match with errors.Is).
+// They are kept apart from dial/auth errors on purpose: a mismatch may be a MITM.
+var (
+	ErrHostKeyMismatch = errors.New("host key mismatch")
+	ErrHostKeyUnknown  = errors.New("host key unknown")
+	ErrHostKeyRevoked  = errors.New("host key revoked")
+	ErrHostCertInvalid = errors.New("host certificate invalid")
+)
+
+// HostKeyStatus is the outcome of the last host key check for a host.
+type HostKeyStatus struct {
+	KeyType     string
+	Fingerprint string // SHA256 of the key the server presented (cert: of the certified key)
+	Changed     bool   // presented key differs from the trusted one
+
+	CertValidBefore time.Time // zero unless a host certificate with an expiry was presented
+}
+
+// hostKeyVerifier checks server host keys against known_hosts or a TOFU store
+// and remembers the last result per host for metrics.
+// Endpoints may name their own known_hosts files (ssh_config UserKnownHostsFile);
+// those take precedence over the global policy.
+// Host certificates signed by one of cas are accepted in either mode.
+type hostKeyVerifier struct {
+	insecure bool
+	known    ssh.HostKeyCallback // global known_hosts; nil in TOFU mode
+	tofu     *tofuStore
+
+	cas   []ssh.PublicKey
+	certs *ssh.CertChecker
+
+	mu     sync.Mutex
+	status map[string]HostKeyStatus
+	files  map[string]ssh.HostKeyCallback // per-endpoint known_hosts, loaded on first use
+}
+
+// newHostKeyVerifier builds the host key policy from cfg:
+// insecure > TOFU (SSH_TOFU_FILE) > known_hosts.
+func newHostKeyVerifier(cfg Config) (*hostKeyVerifier, error) {
+	v := &hostKeyVerifier{
+		status: make(map[string]HostKeyStatus),
+		files:  make(map[string]ssh.HostKeyCallback),
+	}
+	if cfg.InsecureSkipHostKey {
+		v.insecure = true
+		return v, nil
+	}
+
+	if len(cfg.HostCAFiles) > 0 {
+		cas, err := loadCAKeys(cfg.HostCAFiles)
+		if err != nil {
+			return nil, err
+		}
+		v.cas = cas
+		v.certs = &ssh.CertChecker{
+			IsHostAuthority: func(auth ssh.PublicKey, _ string) bool { return v.isHostCA(auth) },
+		}
+	}
+
+	switch {
+	case cfg.TOFUFile != "":
+		st, err := loadTOFUStore(cfg.TOFUFile)
+		if err != nil {
+			return nil, err
+		}
+		v.tofu = st
+		return v, nil
+
+	default:
+		if len(cfg.KnownHostsFiles) == 0 {
+			return nil, fmt.Errorf("no known_hosts files (set SSH_KNOWN_HOSTS, SSH_TOFU_FILE or SSH_INSECURE_SKIP_HOSTKEY=1)")
+		}
+		files := cfg.KnownHostsFiles
+		if cfg.KnownHostsDefault {
+			// images without a home dir file (distroless) must still start;
+			// every target reports hostkey_unknown until keys are provided
+			if _, err := os.Stat(files[0]); errors.Is(err, fs.ErrNotExist) {
+				log.Printf("WARNING: %s does not exist, no host key is trusted (set SSH_KNOWN_HOSTS, SSH_TOFU_FILE or SSH_HOST_CA_KEYS)", files[0])
+				files = nil
+			}
+		}
+		cb, err := knownhosts.New(files...)
+		if err != nil {
+			return nil, fmt.Errorf("load known_hosts: %w", err)
+		}
+		v.known = cb
+		return v, nil
+	}
+}
+
+// Callback returns the host key callback for an endpoint; files overrides
+// the global known_hosts/TOFU policy when non-empty. id is the endpoint's
+// hostID: TOFU pins and the recorded status are kept under it, while
+// known_hosts files and certificates are matched on the dialed hostname.
+func (v *hostKeyVerifier) Callback(files []string, id string) ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		if v.insecure {
+			v.record(id, key, false)
+			return nil
+		}
+
+		if cert, ok := key.(*ssh.Certificate); ok && v.isHostCA(cert.SignatureKey) {
+			err := v.certs.CheckHostKey(hostname, remote, key)
+			if err != nil {
+				err = fmt.Errorf("%w: %s: %w", ErrHostCertInvalid, id, err)
+			}
+			v.record(id, key, false)
+			return err
+		}
+
+		var err error
+		if len(files) == 0 && v.tofu != nil {
+			err = v.tofu.Check(id, remote, key)
+		} else {
+			known, kerr := v.knownFor(files)
+			if kerr != nil {
+				return kerr
+			}
+			err = known(hostname, remote, key)
+		}
+		err = wrapHostKeyError(id, key, err)
+		v.record(id, key, errors.Is(err, ErrHostKeyMismatch))
+		return err
+	}
+}
+
+// knownFor returns the known_hosts callback for files (global one when empty).
+func (v *hostKeyVerifier) knownFor(files []string) (ssh.HostKeyCallback, error) {
+	if len(files) == 0 {
+		return v.known, nil
+	}
+
+	key := strings.Join(files, "\x00")
+	v.mu.Lock()
+	cb, ok := v.files[key]
+	v.mu.Unlock()
+	if ok {
+		return cb, nil
+	}
+
+	cb, err := knownhosts.New(files...)
+	if err != nil {
+		return nil, fmt.Errorf("load known_hosts: %w", err)
+	}
+	v.mu.Lock()
+	v.files[key] = cb
+	v.mu.Unlock()
+	return cb, nil
+}
+
+// Status returns the last host key check result for id (see Client.hostID).
+func (v *hostKeyVerifier) Status(id string) (HostKeyStatus, bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	st, ok := v.status[id]
+	return st, ok
+}
+
+func (v *hostKeyVerifier) record(id string, key ssh.PublicKey, changed bool) {
+	st := HostKeyStatus{
+		KeyType:     key.Type(),
+		Fingerprint: ssh.FingerprintSHA256(key),
+		Changed:     changed,
+	}
+	if cert, ok := key.(*ssh.Certificate); ok {
+		// fingerprint the certified key so re-issuing a cert does not look like a change
+		st.Fingerprint = ssh.FingerprintSHA256(cert.Key)
+		if cert.ValidBefore != ssh.CertTimeInfinity {
+			st.CertValidBefore = time.Unix(int64(cert.ValidBefore), 0)
+		}
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.status[id] = st
+}
+
+func (v *hostKeyVerifier) isHostCA(auth ssh.PublicKey) bool {
+	for _, ca := range v.cas {
+		if bytes.Equal(ca.Marshal(), auth.Marshal()) {
+			return true
+		}
+	}
+	return false
+}
+
+// Algorithms returns host key algorithms for the keys we trust for hostname
+// (TOFU: for id), so the server is asked for a key type we can actually
+// verify (like OpenSSH does). nil means "use defaults".
+func (v *hostKeyVerifier) Algorithms(files []string, hostname, id string, remote net.Addr) []string {
+	if v.insecure {
+		return nil
+	}
+	algos := v.trustedAlgorithms(files, hostname, id, remote)
+	if algos != nil && len(v.cas) > 0 {
+		// prefer certificates when we have a CA to check them with
+		algos = append(hostCertAlgorithms(), algos...)
+	}
+	return algos
+}
+
+func (v *hostKeyVerifier) trustedAlgorithms(files []string, hostname, id string, remote net.Addr) []string {
+	if len(files) == 0 && v.tofu != nil {
+		if k, ok := v.tofu.Lookup(id); ok {
+			return algorithmsForKeyType(k.Type())
+		}
+		if len(v.cas) > 0 {
+			return nil
+		}
+		// never pin a certificate: it changes on every re-issue
+		return plainHostKeyAlgorithms()
+	}
+	known, err := v.knownFor(files)
+	if err != nil {
+		return nil
+	}
+	var ke *knownhosts.KeyError
+	if !errors.As(known(hostname, remote, probeKey{}), &ke) {
+		return nil
+	}
+
+	var algos []string
+	for _, k := range ke.Want {
+		algos = append(algos, algorithmsForKeyType(k.Key.Type())...)
+	}
+	return algos
+}
+
+func wrapHostKeyError(hostname string, key ssh.PublicKey, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var ke *knownhosts.KeyError
+	if errors.As(err, &ke) {
+		if len(ke.Want) > 0 {
+			return fmt.Errorf("%w: %s presented %s %s (known: %s)",
+				ErrHostKeyMismatch, hostname, key.Type(), ssh.FingerprintSHA256(key), ke.Want[0].String())
+		}
+		return fmt.Errorf("%w: %s not trusted (%s %s)",
+			ErrHostKeyUnknown, hostname, key.Type(), ssh.FingerprintSHA256(key))
+	}
+
+	var re *knownhosts.RevokedError
+	if errors.As(err, &re) {
+		return fmt.Errorf("%w: %s (%s)", ErrHostKeyRevoked, hostname, re.Revoked.String())
+	}
+	return err
+}
+
+func algorithmsForKeyType(t string) []string {
+	switch t {
+	case ssh.KeyAlgoRSA:
+		return []string{ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSA}
+	case ssh.CertAlgoRSAv01:
+		return []string{ssh.CertAlgoRSASHA512v01, ssh.CertAlgoRSASHA256v01, ssh.CertAlgoRSAv01}
+	default:
+		return []string{t}
+	}
+}
+
+func hostCertAlgorithms() []string {
+	var out []string
+	for _, a := range ssh.SupportedAlgorithms().HostKeys {
+		if strings.Contains(a, "-cert-") {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+func plainHostKeyAlgorithms() []string {
+	var out []string
+	for _, a := range ssh.SupportedAlgorithms().HostKeys {
+		if !strings.Contains(a, "-cert-") {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// loadCAKeys reads CA public keys, one per line in authorized_keys format.
+func loadCAKeys(files []string) ([]ssh.PublicKey, error) {
+	var out []ssh.PublicKey
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("read host CA keys: %w", err)
+		}
+		sc := bufio.NewScanner(bytes.NewReader(b))
+		line := 0
+		for sc.Scan() {
+			line++
+			ln := bytes.TrimSpace(sc.Bytes())
+			if len(ln) == 0 || ln[0] == '#' {
+				continue
+			}
+			k, _, _, _, err := ssh.ParseAuthorizedKey(ln)
+			if err != nil {
+				return nil, fmt.Errorf("host CA keys %s:%d: %w", f, line, err)
+			}
+			out = append(out, k)
+		}
+		if err := sc.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// probeKey never matches a known_hosts entry; used to list the known keys.
+type probeKey struct{}
+
+func (probeKey) Type() string                            { return "probe" }
+func (probeKey) Marshal() []byte                         { return []byte("probe") }
+func (probeKey) Verify(_ []byte, _ *ssh.Signature) error { return errors.New("probe key") }
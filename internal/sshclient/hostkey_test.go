@@ -0,0 +1,111 @@
+package sshclient
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestHostKeyVerifierMissingKnownHosts(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "known_hosts")
+
+	key := newTestHostKey(t)
+	remote := &net.TCPAddr{IP: net.IPv4(10, 0, 0, 5), Port: 22}
+
+	// the ~/.ssh/known_hosts fallback may be absent: start, trust nothing
+	v, err := newHostKeyVerifier(Config{KnownHostsFiles: []string{missing}, KnownHostsDefault: true})
+	if err != nil {
+		t.Fatalf("default known_hosts missing: %v", err)
+	}
+	err = v.Callback(nil, "10.0.0.5")("10.0.0.5:22", remote, key)
+	if !errors.Is(err, ErrHostKeyUnknown) {
+		t.Errorf("err = %v, want ErrHostKeyUnknown", err)
+	}
+
+	// a file named in SSH_KNOWN_HOSTS must exist
+	if _, err := newHostKeyVerifier(Config{KnownHostsFiles: []string{missing}}); err == nil {
+		t.Error("explicit known_hosts missing: no error")
+	}
+	if _, err := newHostKeyVerifier(Config{}); err == nil {
+		t.Error("no known_hosts files: no error")
+	}
+}
+
+func TestHostIDPerRoute(t *testing.T) {
+	c := &Client{cfg: Config{Port: 22}}
+	siteA := []Endpoint{{Host: "bastion-a.example.net"}}
+	siteB := []Endpoint{{Host: "bastion-b.example.net", Port: 2200}}
+	host := Endpoint{Host: "10.0.0.5"}
+
+	tests := []struct {
+		route []Endpoint
+		e     Endpoint
+		want  string
+	}{
+		{nil, host, "10.0.0.5"},
+		{nil, Endpoint{Host: "10.0.0.5", Port: 2222}, "[10.0.0.5]:2222"},
+		{siteA, host, "bastion-a.example.net/10.0.0.5"},
+		{siteB, host, "[bastion-b.example.net]:2200/10.0.0.5"},
+		{siteA, Endpoint{Host: "fd00::5"}, "bastion-a.example.net/fd00::5"},
+	}
+	for _, tt := range tests {
+		if got := c.hostID(tt.route, tt.e); got != tt.want {
+			t.Errorf("hostID(%v, %v) = %q, want %q", tt.route, tt.e, got, tt.want)
+		}
+	}
+}
+
+func TestTOFUSameAddressBehindBastions(t *testing.T) {
+	state := filepath.Join(t.TempDir(), "tofu")
+	v, err := newHostKeyVerifier(Config{TOFUFile: state})
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyA, keyB := newTestHostKey(t), newTestHostKey(t)
+	remote := &net.TCPAddr{IP: net.IPv4(10, 0, 0, 5), Port: 22}
+
+	// 10.0.0.5 at two sites: two hosts, two pins
+	if err := v.Callback(nil, "bastion-a/10.0.0.5")("10.0.0.5:22", remote, keyA); err != nil {
+		t.Fatalf("site a: %v", err)
+	}
+	if err := v.Callback(nil, "bastion-b/10.0.0.5")("10.0.0.5:22", remote, keyB); err != nil {
+		t.Fatalf("site b: %v", err)
+	}
+	for id, key := range map[string]ssh.PublicKey{"bastion-a/10.0.0.5": keyA, "bastion-b/10.0.0.5": keyB} {
+		st, ok := v.Status(id)
+		if !ok || st.Changed || st.Fingerprint != ssh.FingerprintSHA256(key) {
+			t.Errorf("Status(%s) = %+v, %v", id, st, ok)
+		}
+	}
+
+	// the pins survive a restart and still tell the sites apart
+	v, err = newHostKeyVerifier(Config{TOFUFile: state})
+	if err != nil {
+		t.Fatalf("reload state: %v", err)
+	}
+	if err := v.Callback(nil, "bastion-b/10.0.0.5")("10.0.0.5:22", remote, keyB); err != nil {
+		t.Errorf("site b after reload: %v", err)
+	}
+	err = v.Callback(nil, "bastion-b/10.0.0.5")("10.0.0.5:22", remote, keyA)
+	if !errors.Is(err, ErrHostKeyMismatch) {
+		t.Errorf("site b with site a's key: err = %v, want ErrHostKeyMismatch", err)
+	}
+}
+
+func newTestHostKey(t *testing.T) ssh.PublicKey {
+	t.Helper()
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
@@ -0,0 +1,115 @@
+package sshclient
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Key loading errors (match with errors.Is).
+var (
+	ErrKeyUnreadable  = errors.New("private key unreadable")
+	ErrKeyPassphrase  = errors.New("bad private key passphrase")
+	ErrKeyUnsupported = errors.New("unsupported private key")
+)
+
+// LoadPrivateKey reads an OpenSSH/PEM private key (RSA, ECDSA, Ed25519) from path.
+// passphrase is only used when the key is encrypted.
+func LoadPrivateKey(path, passphrase string) (ssh.Signer, error) {
+	if path == "" {
+		return nil, fmt.Errorf("ssh key path is empty")
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", ErrKeyUnreadable, path, err)
+	}
+
+	var signer ssh.Signer
+	if passphrase != "" {
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(b, []byte(passphrase))
+		if err != nil && !errors.Is(err, x509.IncorrectPasswordError) {
+			// a passphrase configured for a key that is not encrypted is
+			// harmless; x/crypto rejects it, so parse the key as is
+			if plain, perr := ssh.ParsePrivateKey(b); perr == nil {
+				signer, err = plain, nil
+			}
+		}
+	} else {
+		signer, err = ssh.ParsePrivateKey(b)
+	}
+	if err != nil {
+		var missing *ssh.PassphraseMissingError
+		switch {
+		case errors.As(err, &missing):
+			return nil, fmt.Errorf("%w: %s: key is encrypted, set key_passphrase_env or key_passphrase_file", ErrKeyPassphrase, path)
+		case errors.Is(err, x509.IncorrectPasswordError):
+			return nil, fmt.Errorf("%w: %s: incorrect passphrase", ErrKeyPassphrase, path)
+		default:
+			return nil, fmt.Errorf("%w: %s: %w", ErrKeyUnsupported, path, err)
+		}
+	}
+
+	switch signer.PublicKey().Type() {
+	case ssh.KeyAlgoRSA, ssh.KeyAlgoECDSA256, ssh.KeyAlgoECDSA384, ssh.KeyAlgoECDSA521, ssh.KeyAlgoED25519:
+		return signer, nil
+	default:
+		return nil, fmt.Errorf("%w: %s: key type %q", ErrKeyUnsupported, path, signer.PublicKey().Type())
+	}
+}
+
+// LoadUserCertificate reads an OpenSSH user certificate (e.g. id_ed25519-cert.pub).
+func LoadUserCertificate(path string) (*ssh.Certificate, error) {
+	if path == "" {
+		return nil, fmt.Errorf("ssh certificate path is empty")
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", ErrKeyUnreadable, path, err)
+	}
+	pub, _, _, _, err := ssh.ParseAuthorizedKey(b)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", ErrKeyUnsupported, path, err)
+	}
+	cert, ok := pub.(*ssh.Certificate)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s: not a certificate (%s)", ErrKeyUnsupported, path, pub.Type())
+	}
+	if cert.CertType != ssh.UserCert {
+		return nil, fmt.Errorf("%w: %s: not a user certificate", ErrKeyUnsupported, path)
+	}
+	return cert, nil
+}
+
+// CertExpiry returns the ValidBefore time of the user certificate at path
+// (zero time for certificates that never expire).
+func CertExpiry(path string) (time.Time, error) {
+	cert, err := LoadUserCertificate(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if cert.ValidBefore == ssh.CertTimeInfinity {
+		return time.Time{}, nil
+	}
+	return time.Unix(int64(cert.ValidBefore), 0), nil
+}
+
+// LoadCertSigner pairs the user certificate at certPath with its private key.
+func LoadCertSigner(certPath, keyPath, passphrase string) (ssh.Signer, error) {
+	cert, err := LoadUserCertificate(certPath)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := LoadPrivateKey(keyPath, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	cs, err := ssh.NewCertSigner(cert, signer)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s does not match %s: %w", ErrKeyUnsupported, certPath, keyPath, err)
+	}
+	return cs, nil
+}
@@ -24,6 +24,53 @@ func ParseLoad1(out string) (float64, error) {
 	return strconv.ParseFloat(fields[0], 64)
 }
 
+// Loadavg is /proc/loadavg. Some kernels and container runtimes (LXCFS,
+// gVisor, old 2.4 kernels) leave out the trailing fields; Has* say which
+// of them were present.
+type Loadavg struct {
+	Load1, Load5, Load15 float64
+
+	Running, Total int // runnable / existing scheduling entities
+	HasEntities    bool
+
+	LastPID    int
+	HasLastPID bool
+}
+
+// ParseLoadavg parses "0.10 0.20 0.30 1/123 4567".
+func ParseLoadavg(out string) (Loadavg, error) {
+	var la Loadavg
+	fields := strings.Fields(out)
+	if len(fields) < 3 {
+		return la, fmt.Errorf("bad loadavg: %q", out)
+	}
+	for i, p := range []*float64{&la.Load1, &la.Load5, &la.Load15} {
+		v, err := strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			return la, fmt.Errorf("bad loadavg: %q", out)
+		}
+		*p = v
+	}
+
+	if len(fields) > 3 {
+		r, t, ok := strings.Cut(fields[3], "/")
+		running, err1 := strconv.Atoi(r)
+		total, err2 := strconv.Atoi(t)
+		if !ok || err1 != nil || err2 != nil {
+			return la, fmt.Errorf("bad loadavg entities: %q", fields[3])
+		}
+		la.Running, la.Total, la.HasEntities = running, total, true
+	}
+	if len(fields) > 4 {
+		pid, err := strconv.Atoi(fields[4])
+		if err != nil {
+			return la, fmt.Errorf("bad loadavg last pid: %q", fields[4])
+		}
+		la.LastPID, la.HasLastPID = pid, true
+	}
+	return la, nil
+}
+
 func ParseMeminfo(out string) (totalBytes, availBytes float64, err error) {
 	// ต้องการ MemTotal + MemAvailable (kB)
 	var totalKB, availKB float64
@@ -53,3 +100,120 @@ func parseMeminfoKB(line string) (float64, error) {
 	}
 	return strconv.ParseFloat(fields[1], 64)
 }
+
+// MeminfoField is one /proc/meminfo line. Name is sanitized the way
+// node_exporter does it ("Active(anon)" -> "Active_anon").
+type MeminfoField struct {
+	Name  string
+	Value float64 // bytes when Bytes, otherwise a plain count (HugePages_*)
+	Bytes bool
+}
+
+// ParseMeminfoFields returns every field of /proc/meminfo in file order.
+// Values in kB are converted to bytes.
+func ParseMeminfoFields(out string) ([]MeminfoField, error) {
+	var fields []MeminfoField
+	for _, ln := range strings.Split(out, "\n") {
+		ln = strings.TrimSpace(ln)
+		if ln == "" {
+			continue
+		}
+		// "MemTotal:       4015356 kB" / "HugePages_Total:       0"
+		name, rest, ok := strings.Cut(ln, ":")
+		parts := strings.Fields(rest)
+		if !ok || len(parts) == 0 || len(parts) > 2 {
+			return nil, fmt.Errorf("bad meminfo line: %q", ln)
+		}
+		v, err := strconv.ParseFloat(parts[0], 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad meminfo line: %q", ln)
+		}
+		f := MeminfoField{Name: meminfoName(name), Value: v}
+		if len(parts) == 2 {
+			if parts[1] != "kB" {
+				return nil, fmt.Errorf("bad meminfo unit: %q", ln)
+			}
+			f.Value *= 1024
+			f.Bytes = true
+		}
+		fields = append(fields, f)
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty meminfo")
+	}
+	return fields, nil
+}
+
+func meminfoName(s string) string {
+	s = strings.TrimSpace(s)
+	s = strings.ReplaceAll(s, "(", "_")
+	s = strings.ReplaceAll(s, ")", "")
+	return s
+}
+
+// NetDev is one interface line of /proc/net/dev.
+type NetDev struct {
+	Device   string
+	Receive  []NetDevCounter
+	Transmit []NetDevCounter
+}
+
+// NetDevCounter is one column, named after the header ("bytes", "packets", ...).
+type NetDevCounter struct {
+	Name  string
+	Value float64
+}
+
+// default columns when the header is missing or unreadable
+var (
+	netDevReceive  = []string{"bytes", "packets", "errs", "drop", "fifo", "frame", "compressed", "multicast"}
+	netDevTransmit = []string{"bytes", "packets", "errs", "drop", "fifo", "colls", "carrier", "compressed"}
+)
+
+// ParseNetDev parses /proc/net/dev. Column names come from the " face |"
+// header line, so kernels with fewer or extra columns work. The device name
+// ends at the last colon ("eth0:1" aliases) and may run into the first
+// number ("eth0:123456").
+func ParseNetDev(out string) ([]NetDev, error) {
+	rx, tx := netDevReceive, netDevTransmit
+
+	var devs []NetDev
+	for _, ln := range strings.Split(out, "\n") {
+		if strings.TrimSpace(ln) == "" {
+			continue
+		}
+		if strings.Contains(ln, "|") {
+			// "Inter-|   Receive ... |  Transmit" / " face |bytes ... |bytes ..."
+			parts := strings.Split(ln, "|")
+			if len(parts) == 3 && strings.TrimSpace(parts[0]) == "face" {
+				rx, tx = strings.Fields(parts[1]), strings.Fields(parts[2])
+			}
+			continue
+		}
+
+		i := strings.LastIndex(ln, ":")
+		if i < 0 {
+			return nil, fmt.Errorf("bad net/dev line: %q", ln)
+		}
+		dev := strings.TrimSpace(ln[:i])
+		vals := strings.Fields(ln[i+1:])
+		if dev == "" || len(vals) != len(rx)+len(tx) {
+			return nil, fmt.Errorf("bad net/dev line: %q", ln)
+		}
+
+		d := NetDev{Device: dev}
+		for j, v := range vals {
+			n, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, fmt.Errorf("bad net/dev value %q for %s", v, dev)
+			}
+			if j < len(rx) {
+				d.Receive = append(d.Receive, NetDevCounter{Name: rx[j], Value: n})
+			} else {
+				d.Transmit = append(d.Transmit, NetDevCounter{Name: tx[j-len(rx)], Value: n})
+			}
+		}
+		devs = append(devs, d)
+	}
+	return devs, nil
+}
@@ -0,0 +1,262 @@
+package sshclient
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseLoadavg(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    Loadavg
+		wantErr bool
+	}{
+		{
+			name: "standard",
+			in:   "0.10 0.20 0.30 1/123 4567\n",
+			want: Loadavg{Load1: 0.10, Load5: 0.20, Load15: 0.30,
+				Running: 1, Total: 123, HasEntities: true, LastPID: 4567, HasLastPID: true},
+		},
+		{
+			name: "lxcfs/gvisor three fields",
+			in:   "0.00 0.01 0.05",
+			want: Loadavg{Load1: 0, Load5: 0.01, Load15: 0.05},
+		},
+		{
+			name: "entities without last pid",
+			in:   "1.50 2 3 4/5",
+			want: Loadavg{Load1: 1.5, Load5: 2, Load15: 3, Running: 4, Total: 5, HasEntities: true},
+		},
+		{
+			name: "trailing whitespace",
+			in:   "  0.50 0.40 0.30 2/300 999 \t\n\n",
+			want: Loadavg{Load1: 0.5, Load5: 0.4, Load15: 0.3,
+				Running: 2, Total: 300, HasEntities: true, LastPID: 999, HasLastPID: true},
+		},
+		{name: "entities without slash", in: "0.1 0.2 0.3 12 4567", wantErr: true},
+		{name: "entities not numeric", in: "0.1 0.2 0.3 a/b 4567", wantErr: true},
+		{name: "entities missing total", in: "0.1 0.2 0.3 1/ 4567", wantErr: true},
+		{name: "bad last pid", in: "0.1 0.2 0.3 1/2 x", wantErr: true},
+		{name: "too few fields", in: "0.1 0.2", wantErr: true},
+		{name: "empty", in: "", wantErr: true},
+		{name: "not a number", in: "x y z", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLoadavg(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseLoadavg(%q) = %+v, want error", tt.in, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseLoadavg(%q): %v", tt.in, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseLoadavg(%q) = %+v, want %+v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+// netDevCols builds counters for names from vals in order.
+func netDevCols(names []string, vals ...float64) []NetDevCounter {
+	cs := make([]NetDevCounter, len(names))
+	for i, n := range names {
+		cs[i] = NetDevCounter{Name: n, Value: vals[i]}
+	}
+	return cs
+}
+
+func TestParseNetDev(t *testing.T) {
+	const header = "Inter-|   Receive                                                |  Transmit\n" +
+		" face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed\n"
+	seq := func(from float64) []float64 {
+		v := make([]float64, 8)
+		for i := range v {
+			v[i] = from + float64(i)
+		}
+		return v
+	}
+	dev := func(name string, rx, tx float64) NetDev {
+		return NetDev{Device: name, Receive: netDevCols(netDevReceive, seq(rx)...), Transmit: netDevCols(netDevTransmit, seq(tx)...)}
+	}
+
+	tests := []struct {
+		name    string
+		in      string
+		want    []NetDev
+		wantErr bool
+	}{
+		{
+			name: "standard",
+			in: header +
+				"    lo:       1    2    3    4    5    6    7    8       11   12   13   14   15   16   17   18\n" +
+				"  eth0:     100  101  102  103  104  105  106  107      200  201  202  203  204  205  206  207\n",
+			want: []NetDev{dev("lo", 1, 11), dev("eth0", 100, 200)},
+		},
+		{
+			name: "alias with colon",
+			in:   header + "eth0:1:     100  101  102  103  104  105  106  107      200  201  202  203  204  205  206  207\n",
+			want: []NetDev{dev("eth0:1", 100, 200)},
+		},
+		{
+			name: "bytes run into the name",
+			in:   header + "  eth0:123456 101  102  103  104  105  106  107      200  201  202  203  204  205  206  207\n",
+			want: []NetDev{{
+				Device:   "eth0",
+				Receive:  netDevCols(netDevReceive, 123456, 101, 102, 103, 104, 105, 106, 107),
+				Transmit: netDevCols(netDevTransmit, seq(200)...),
+			}},
+		},
+		{
+			name: "alias with bytes run into the name",
+			in:   header + "eth0:1:123456 101 102 103 104 105 106 107 200 201 202 203 204 205 206 207",
+			want: []NetDev{{
+				Device:   "eth0:1",
+				Receive:  netDevCols(netDevReceive, 123456, 101, 102, 103, 104, 105, 106, 107),
+				Transmit: netDevCols(netDevTransmit, seq(200)...),
+			}},
+		},
+		{
+			name: "header with fewer columns",
+			in: "Inter-|   Receive                 |  Transmit\n" +
+				" face |bytes    packets errs drop|bytes    packets errs drop\n" +
+				"  eth0: 1 2 3 4 5 6 7 8\n",
+			want: []NetDev{{
+				Device:   "eth0",
+				Receive:  netDevCols([]string{"bytes", "packets", "errs", "drop"}, 1, 2, 3, 4),
+				Transmit: netDevCols([]string{"bytes", "packets", "errs", "drop"}, 5, 6, 7, 8),
+			}},
+		},
+		{
+			name: "header with extra columns",
+			in: "Inter-|   Receive   |  Transmit\n" +
+				" face |bytes packets nohandler|bytes packets carrier_changes\n" +
+				"  eth0: 1 2 3 4 5 6\n",
+			want: []NetDev{{
+				Device:   "eth0",
+				Receive:  netDevCols([]string{"bytes", "packets", "nohandler"}, 1, 2, 3),
+				Transmit: netDevCols([]string{"bytes", "packets", "carrier_changes"}, 4, 5, 6),
+			}},
+		},
+		{
+			name: "no header",
+			in:   "  eth0:     100  101  102  103  104  105  106  107      200  201  202  203  204  205  206  207\n",
+			want: []NetDev{dev("eth0", 100, 200)},
+		},
+		{name: "empty", in: "\n\n", want: nil},
+		{
+			name:    "column count mismatch",
+			in:      header + "  eth0: 1 2 3 4 5 6 7 8 9 10 11 12 13 14 15\n",
+			wantErr: true,
+		},
+		{
+			name:    "fewer values than the header",
+			in:      "Inter-|Receive|Transmit\n face |bytes packets|bytes packets\n eth0: 1 2 3\n",
+			wantErr: true,
+		},
+		{name: "no colon", in: header + "  eth0 1 2 3 4 5 6 7 8 9 10 11 12 13 14 15 16\n", wantErr: true},
+		{name: "no device name", in: header + "  : 1 2 3 4 5 6 7 8 9 10 11 12 13 14 15 16\n", wantErr: true},
+		{name: "not a number", in: header + "  eth0: 1 2 3 4 5 6 7 x 9 10 11 12 13 14 15 16\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseNetDev(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseNetDev(%q) = %+v, want error", tt.in, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseNetDev(%q): %v", tt.in, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseNetDev(%q)\n got %+v\nwant %+v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMeminfoFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    []MeminfoField
+		wantErr bool
+	}{
+		{
+			name: "kB to bytes",
+			in:   "MemTotal:       4015356 kB\nMemAvailable:   2000000 kB\n",
+			want: []MeminfoField{
+				{Name: "MemTotal", Value: 4015356 * 1024, Bytes: true},
+				{Name: "MemAvailable", Value: 2000000 * 1024, Bytes: true},
+			},
+		},
+		{
+			name: "node_exporter names",
+			in:   "Active(anon):     1024 kB\nInactive(file):      0 kB\n",
+			want: []MeminfoField{
+				{Name: "Active_anon", Value: 1024 * 1024, Bytes: true},
+				{Name: "Inactive_file", Value: 0, Bytes: true},
+			},
+		},
+		{
+			name: "unit-less hugepages",
+			in:   "HugePages_Total:      16\nHugePages_Free:        4\nHugepagesize:       2048 kB\n",
+			want: []MeminfoField{
+				{Name: "HugePages_Total", Value: 16},
+				{Name: "HugePages_Free", Value: 4},
+				{Name: "Hugepagesize", Value: 2048 * 1024, Bytes: true},
+			},
+		},
+		{
+			name: "blank lines and padding",
+			in:   "\n  MemFree:  10 kB  \n\n",
+			want: []MeminfoField{{Name: "MemFree", Value: 10 * 1024, Bytes: true}},
+		},
+		{name: "bad unit", in: "MemTotal: 4015356 MB\n", wantErr: true},
+		{name: "not a number", in: "MemTotal: lots kB\n", wantErr: true},
+		{name: "no colon", in: "MemTotal 4015356 kB\n", wantErr: true},
+		{name: "no value", in: "MemTotal:\n", wantErr: true},
+		{name: "too many fields", in: "MemTotal: 1 kB extra\n", wantErr: true},
+		{name: "empty", in: "\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseMeminfoFields(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseMeminfoFields(%q) = %+v, want error", tt.in, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseMeminfoFields(%q): %v", tt.in, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseMeminfoFields(%q)\n got %+v\nwant %+v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMeminfoName(t *testing.T) {
+	for in, want := range map[string]string{
+		"MemTotal":        "MemTotal",
+		"Active(anon)":    "Active_anon",
+		"Inactive(file)":  "Inactive_file",
+		" HugePages_Rsvd": "HugePages_Rsvd",
+		"DirectMap4k":     "DirectMap4k",
+	} {
+		if got := meminfoName(in); got != want {
+			t.Errorf("meminfoName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package sshclient
+
+import (
+	"bytes"
+	"errors"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// DefaultMaxOutputBytes caps stdout and stderr (each) of one command.
+const DefaultMaxOutputBytes = 1 << 20
+
+// Output is what a remote command produced. A command that ran but exited
+// non-zero is not an Exec error; check ExitCode/Signal.
+type Output struct {
+	Stdout string
+	Stderr string
+
+	ExitCode int    // -1 when the server sent no exit status
+	Signal   string // signal name without "SIG" ("KILL"), "" if none
+
+	Truncated bool // stdout or stderr hit the size cap
+}
+
+// OK reports a clean exit.
+func (o Output) OK() bool { return o.ExitCode == 0 && o.Signal == "" }
+
+// exitStatus fills ExitCode/Signal from the error returned by Session.Run.
+// It reports false for errors that are not about the remote exit status.
+func (o *Output) exitStatus(err error) bool {
+	var ee *ssh.ExitError
+	var em *ssh.ExitMissingError
+	switch {
+	case err == nil:
+		o.ExitCode = 0
+	case errors.As(err, &ee):
+		o.ExitCode = ee.ExitStatus()
+		o.Signal = ee.Signal()
+	case errors.As(err, &em):
+		o.ExitCode = -1
+	default:
+		return false
+	}
+	return true
+}
+
+// cappedBuffer keeps the first max bytes and discards the rest
+// (still reading it, so the remote side does not block on a full window).
+type cappedBuffer struct {
+	buf       bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	if room := b.max - b.buf.Len(); room < len(p) {
+		b.truncated = true
+		if room <= 0 {
+			return n, nil
+		}
+		p = p[:room]
+	}
+	b.buf.Write(p)
+	return n, nil
+}
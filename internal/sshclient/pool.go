@@ -0,0 +1,178 @@
+package sshclient
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// PoolStats is a snapshot of connection pool counters.
+type PoolStats struct {
+	Connections int    // open pooled connections (targets + bastions)
+	Dials       uint64 // new connections established
+	Reused      uint64 // commands/tunnels served by an existing connection
+	Reconnects  uint64 // connections re-established after the previous one went bad
+}
+
+// pool keeps authenticated connections keyed by chainKey
+// (target + user + auth, including the jump route to it).
+type pool struct {
+	mu    sync.Mutex
+	conns map[string]*ssh.Client
+	seen  map[string]bool // keys connected at least once (for reconnect counting)
+
+	dials      uint64
+	reused     uint64
+	reconnects uint64
+}
+
+func newPool() *pool {
+	return &pool{
+		conns: make(map[string]*ssh.Client),
+		seen:  make(map[string]bool),
+	}
+}
+
+func (p *pool) get(key string) (*ssh.Client, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	cl, ok := p.conns[key]
+	if ok {
+		atomic.AddUint64(&p.reused, 1)
+	}
+	return cl, ok
+}
+
+// put stores cl unless another worker connected first; the pooled client is returned.
+func (p *pool) put(key string, cl *ssh.Client) (*ssh.Client, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if existing, ok := p.conns[key]; ok {
+		return existing, false
+	}
+	p.conns[key] = cl
+	atomic.AddUint64(&p.dials, 1)
+	if p.seen[key] {
+		atomic.AddUint64(&p.reconnects, 1)
+	}
+	p.seen[key] = true
+	return cl, true
+}
+
+// drop forgets cl (if it is still the pooled connection for key) and closes it.
+func (p *pool) drop(key string, cl *ssh.Client) {
+	p.mu.Lock()
+	if p.conns[key] == cl {
+		delete(p.conns, key)
+	}
+	p.mu.Unlock()
+	_ = cl.Close()
+}
+
+func (p *pool) stats() PoolStats {
+	p.mu.Lock()
+	n := len(p.conns)
+	p.mu.Unlock()
+	return PoolStats{
+		Connections: n,
+		Dials:       atomic.LoadUint64(&p.dials),
+		Reused:      atomic.LoadUint64(&p.reused),
+		Reconnects:  atomic.LoadUint64(&p.reconnects),
+	}
+}
+
+// PoolStats returns connection pool counters.
+func (c *Client) PoolStats() PoolStats { return c.pool.stats() }
+
+// pooled returns an authenticated connection to the last hop of chain,
+// dialing it (and any hop before it) when there is none in the pool.
+// final is false when the last hop is a bastion (errors name the hop).
+func (c *Client) pooled(ctx context.Context, chain []Endpoint, final bool) (*ssh.Client, string, error) {
+	key := c.chainKey(chain)
+	if cl, ok := c.pool.get(key); ok {
+		return cl, key, nil
+	}
+
+	var via *ssh.Client
+	if len(chain) > 1 {
+		v, _, err := c.pooled(ctx, chain[:len(chain)-1], false)
+		if err != nil {
+			return nil, "", err
+		}
+		via = v
+	}
+
+	hop := chain[len(chain)-1]
+	cl, err := c.connect(ctx, via, chain[:len(chain)-1], hop, true)
+	if err != nil {
+		if !final {
+			err = fmt.Errorf("jump %s: %w", c.addr(hop), err)
+		}
+		return nil, "", err
+	}
+
+	pcl, stored := c.pool.put(key, cl)
+	if !stored {
+		// another worker connected first; keep theirs
+		_ = cl.Close()
+		return pcl, key, nil
+	}
+
+	go c.watch(key, cl)
+	return cl, key, nil
+}
+
+// watch sends keepalives on cl and removes it from the pool once it dies,
+// so the next job reconnects.
+func (c *Client) watch(key string, cl *ssh.Client) {
+	done := make(chan struct{})
+	go func() {
+		_ = cl.Wait()
+		close(done)
+	}()
+
+	interval := c.cfg.KeepaliveInterval
+	if interval <= 0 {
+		<-done
+		c.pool.drop(key, cl)
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			c.pool.drop(key, cl)
+			return
+		case <-ticker.C:
+			if err := keepalive(cl, c.cfg.Timeout); err != nil {
+				c.pool.drop(key, cl)
+				return
+			}
+		}
+	}
+}
+
+// keepalive sends an OpenSSH keepalive request; any reply (even a refusal)
+// proves the connection is alive.
+func keepalive(cl *ssh.Client, timeout time.Duration) error {
+	errCh := make(chan error, 1)
+	go func() {
+		_, _, err := cl.SendRequest("keepalive@openssh.com", true, nil)
+		errCh <- err
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case err := <-errCh:
+		return err
+	case <-timer.C:
+		return fmt.Errorf("keepalive timeout after %s", timeout)
+	}
+}
@@ -0,0 +1,281 @@
+package sshclient
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Minimal SFTP v3 client (draft-ietf-secsh-filexfer-02): just enough to
+// read whole files on hosts that only allow the internal-sftp subsystem.
+
+const (
+	sftpInit    = 1
+	sftpVersion = 2
+	sftpOpen    = 3
+	sftpClose   = 4
+	sftpRead    = 5
+	sftpStatus  = 101
+	sftpHandle  = 102
+	sftpData    = 103
+
+	sftpOK  = 0
+	sftpEOF = 1
+
+	sftpFlagRead = 0x00000001
+
+	sftpChunk     = 32 * 1024
+	sftpMaxPacket = 256 * 1024
+)
+
+// sftpStatusError is a failed request (no such file, permission denied, ...).
+type sftpStatusError struct {
+	code uint32
+	msg  string
+}
+
+func (e *sftpStatusError) Error() string {
+	return fmt.Sprintf("sftp status %d: %s", e.code, e.msg)
+}
+
+type sftpConn struct {
+	w  io.Writer
+	r  *bufio.Reader
+	id uint32
+}
+
+// runSFTP reads the file behind every cmd over one SFTP session.
+// A file that cannot be read is reported like a failed "cat": ExitCode 1
+// and the server's message on Stderr.
+func (c *Client) runSFTP(ctx context.Context, t Target, cmds []AllowedCommand) ([]Output, error) {
+	paths := make([]string, len(cmds))
+	for i, cmd := range cmds {
+		spec, ok := cmd.spec()
+		if !ok || spec.path == "" {
+			err := fmt.Errorf("%w (sftp)", ErrUnsupported(cmd))
+			c.audit(t, cmd, "", Output{}, err)
+			return nil, err
+		}
+		paths[i] = spec.path
+	}
+
+	sess, release, err := c.session(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	defer sess.Close()
+
+	stop := context.AfterFunc(ctx, func() { _ = sess.Close() })
+	defer stop()
+
+	started := time.Now()
+	defer func() { traceFrom(ctx).add(PhaseCommand, time.Since(started)) }()
+
+	outs, err := c.sftpReadAll(sess, paths)
+	if ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	for i, cmd := range cmds {
+		var o Output
+		if i < len(outs) {
+			o = outs[i]
+		}
+		c.audit(t, cmd, "sftp read "+paths[i], o, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return outs, nil
+}
+
+func (c *Client) sftpReadAll(sess *ssh.Session, paths []string) ([]Output, error) {
+	w, err := sess.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	r, err := sess.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := sess.RequestSubsystem("sftp"); err != nil {
+		return nil, fmt.Errorf("sftp subsystem: %w", err)
+	}
+
+	sc := &sftpConn{w: w, r: bufio.NewReader(r)}
+	if err := sc.init(); err != nil {
+		return nil, err
+	}
+	return sc.readFiles(paths, c.maxOutput())
+}
+
+// readFiles reads every path, keeping at most max bytes of each. A status
+// error ends only that file; anything else ends the session.
+func (s *sftpConn) readFiles(paths []string, max int) ([]Output, error) {
+	outs := make([]Output, 0, len(paths))
+	for _, p := range paths {
+		data, truncated, err := s.readFile(p, max)
+		var se *sftpStatusError
+		switch {
+		case errors.As(err, &se):
+			outs = append(outs, Output{Stderr: p + ": " + se.msg + "\n", ExitCode: 1})
+		case err != nil:
+			return outs, err
+		default:
+			outs = append(outs, Output{Stdout: string(data), Truncated: truncated})
+		}
+	}
+	return outs, nil
+}
+
+func (s *sftpConn) init() error {
+	if err := s.send(sftpInit, binary.BigEndian.AppendUint32(nil, 3)); err != nil {
+		return err
+	}
+	typ, payload, err := s.recv()
+	if err != nil {
+		return err
+	}
+	if typ != sftpVersion || len(payload) < 4 {
+		return fmt.Errorf("sftp: unexpected packet %d during init", typ)
+	}
+	if v := binary.BigEndian.Uint32(payload); v < 3 {
+		return fmt.Errorf("sftp: server speaks version %d, need 3", v)
+	}
+	return nil
+}
+
+// readFile reads path sequentially until EOF (procfs reports size 0, so
+// attributes cannot be trusted), keeping at most max bytes.
+func (s *sftpConn) readFile(path string, max int) ([]byte, bool, error) {
+	// open: id, filename, pflags, attrs (flags=0)
+	req := appendString(nil, path)
+	req = binary.BigEndian.AppendUint32(req, sftpFlagRead)
+	req = binary.BigEndian.AppendUint32(req, 0)
+	payload, err := s.call(sftpOpen, req, sftpHandle)
+	if err != nil {
+		return nil, false, err
+	}
+	h, _, ok := parseString(payload)
+	if !ok {
+		return nil, false, fmt.Errorf("sftp: malformed handle")
+	}
+	handle := string(h)
+	defer s.closeHandle(handle)
+
+	var (
+		data   []byte
+		offset uint64
+	)
+	for {
+		req := appendString(nil, handle)
+		req = binary.BigEndian.AppendUint64(req, offset)
+		req = binary.BigEndian.AppendUint32(req, sftpChunk)
+		payload, err := s.call(sftpRead, req, sftpData)
+		var se *sftpStatusError
+		if errors.As(err, &se) && se.code == sftpEOF {
+			return data, false, nil
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		chunk, _, ok := parseString(payload)
+		if !ok {
+			return nil, false, fmt.Errorf("sftp: malformed data")
+		}
+		if len(chunk) == 0 {
+			return data, false, nil
+		}
+		offset += uint64(len(chunk))
+		if room := max - len(data); room < len(chunk) {
+			// unlike a shell stream nothing is pushed at us: stop reading
+			return append(data, chunk[:room]...), true, nil
+		}
+		data = append(data, chunk...)
+	}
+}
+
+func (s *sftpConn) closeHandle(handle string) {
+	_, _ = s.call(sftpClose, appendString(nil, handle), sftpStatus)
+}
+
+// call sends one request and waits for its reply (one request in flight).
+// A STATUS reply other than OK is returned as *sftpStatusError.
+func (s *sftpConn) call(typ byte, body []byte, want byte) ([]byte, error) {
+	s.id++
+	id := s.id
+	if err := s.send(typ, append(binary.BigEndian.AppendUint32(nil, id), body...)); err != nil {
+		return nil, err
+	}
+
+	rtyp, payload, err := s.recv()
+	if err != nil {
+		return nil, err
+	}
+	if len(payload) < 4 || binary.BigEndian.Uint32(payload) != id {
+		return nil, fmt.Errorf("sftp: reply id mismatch")
+	}
+	payload = payload[4:]
+
+	if rtyp == sftpStatus {
+		if len(payload) < 4 {
+			return nil, fmt.Errorf("sftp: malformed status")
+		}
+		code := binary.BigEndian.Uint32(payload)
+		msg, _, _ := parseString(payload[4:])
+		if code == sftpOK && want == sftpStatus {
+			return nil, nil
+		}
+		return nil, &sftpStatusError{code: code, msg: string(msg)}
+	}
+	if rtyp != want {
+		return nil, fmt.Errorf("sftp: unexpected packet %d (want %d)", rtyp, want)
+	}
+	return payload, nil
+}
+
+func (s *sftpConn) send(typ byte, payload []byte) error {
+	pkt := binary.BigEndian.AppendUint32(nil, uint32(len(payload)+1))
+	pkt = append(pkt, typ)
+	pkt = append(pkt, payload...)
+	_, err := s.w.Write(pkt)
+	return err
+}
+
+func (s *sftpConn) recv() (byte, []byte, error) {
+	var hdr [4]byte
+	if _, err := io.ReadFull(s.r, hdr[:]); err != nil {
+		return 0, nil, fmt.Errorf("sftp: %w", err)
+	}
+	n := binary.BigEndian.Uint32(hdr[:])
+	if n == 0 || n > sftpMaxPacket {
+		return 0, nil, fmt.Errorf("sftp: bad packet length %d", n)
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(s.r, buf); err != nil {
+		return 0, nil, fmt.Errorf("sftp: %w", err)
+	}
+	return buf[0], buf[1:], nil
+}
+
+func appendString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint32(b, uint32(len(s)))
+	return append(b, s...)
+}
+
+func parseString(b []byte) ([]byte, []byte, bool) {
+	if len(b) < 4 {
+		return nil, nil, false
+	}
+	n := binary.BigEndian.Uint32(b)
+	if uint64(len(b)-4) < uint64(n) {
+		return nil, nil, false
+	}
+	return b[4 : 4+n], b[4+n:], true
+}
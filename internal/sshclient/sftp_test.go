@@ -0,0 +1,294 @@
+package sshclient
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// fakeSFTP is an in-memory SFTP server serving files, one request at a time.
+type fakeSFTP struct {
+	files   map[string][]byte
+	badID   bool // answer every request with the wrong id
+	reads   int  // READ requests seen
+	closes  int  // CLOSE requests seen
+	srvDone chan struct{}
+}
+
+// newFakeSFTP wires an sftpConn to f over two pipes; the server stops when
+// the test ends.
+func newFakeSFTP(t *testing.T, f *fakeSFTP) *sftpConn {
+	t.Helper()
+
+	cr, sw := io.Pipe() // server -> client
+	sr, cw := io.Pipe() // client -> server
+	srv := &sftpConn{w: sw, r: bufio.NewReader(sr)}
+	f.srvDone = make(chan struct{})
+	go func() {
+		defer close(f.srvDone)
+		defer sw.Close()
+		f.serve(srv)
+	}()
+	t.Cleanup(func() {
+		_ = cw.Close()
+		<-f.srvDone
+	})
+	return &sftpConn{w: cw, r: bufio.NewReader(cr)}
+}
+
+func (f *fakeSFTP) serve(srv *sftpConn) {
+	for {
+		typ, payload, err := srv.recv()
+		if err != nil {
+			return
+		}
+		if typ == sftpInit {
+			_ = srv.send(sftpVersion, binary.BigEndian.AppendUint32(nil, 3))
+			continue
+		}
+		id := binary.BigEndian.Uint32(payload)
+		if f.badID {
+			id++
+		}
+		body := payload[4:]
+		reply := binary.BigEndian.AppendUint32(nil, id)
+
+		switch typ {
+		case sftpOpen:
+			path, _, _ := parseString(body)
+			if _, ok := f.files[string(path)]; !ok {
+				_ = srv.send(sftpStatus, status(reply, 2, "No such file"))
+				continue
+			}
+			_ = srv.send(sftpHandle, appendString(reply, string(path)))
+		case sftpRead:
+			h, rest, _ := parseString(body)
+			offset := binary.BigEndian.Uint64(rest)
+			n := binary.BigEndian.Uint32(rest[8:])
+			f.reads++
+			data := f.files[string(h)]
+			if offset >= uint64(len(data)) {
+				_ = srv.send(sftpStatus, status(reply, sftpEOF, "EOF"))
+				continue
+			}
+			end := min(offset+uint64(n), uint64(len(data)))
+			_ = srv.send(sftpData, appendString(reply, string(data[offset:end])))
+		case sftpClose:
+			f.closes++
+			_ = srv.send(sftpStatus, status(reply, sftpOK, ""))
+		default:
+			_ = srv.send(sftpStatus, status(reply, 8, "unsupported"))
+		}
+	}
+}
+
+func status(b []byte, code uint32, msg string) []byte {
+	b = binary.BigEndian.AppendUint32(b, code)
+	b = appendString(b, msg)
+	return appendString(b, "")
+}
+
+func TestSFTPReadFileChunks(t *testing.T) {
+	big := bytes.Repeat([]byte("0123456789abcdef"), sftpChunk/16*2+100) // 2 chunks + a bit
+	f := &fakeSFTP{files: map[string][]byte{"/proc/big": big}}
+	sc := newFakeSFTP(t, f)
+	if err := sc.init(); err != nil {
+		t.Fatalf("init: %v", err)
+	}
+
+	data, truncated, err := sc.readFile("/proc/big", 1<<20)
+	if err != nil {
+		t.Fatalf("readFile: %v", err)
+	}
+	if truncated {
+		t.Error("truncated = true, want false")
+	}
+	if !bytes.Equal(data, big) {
+		t.Errorf("got %d bytes, want %d", len(data), len(big))
+	}
+	// three data chunks, then the EOF status
+	if f.reads != 4 {
+		t.Errorf("reads = %d, want 4", f.reads)
+	}
+	if f.closes != 1 {
+		t.Errorf("closes = %d, want 1", f.closes)
+	}
+}
+
+func TestSFTPReadFileTruncated(t *testing.T) {
+	big := bytes.Repeat([]byte("x"), sftpChunk*3)
+	f := &fakeSFTP{files: map[string][]byte{"/proc/big": big}}
+	sc := newFakeSFTP(t, f)
+
+	max := sftpChunk + 10
+	data, truncated, err := sc.readFile("/proc/big", max)
+	if err != nil {
+		t.Fatalf("readFile: %v", err)
+	}
+	if !truncated {
+		t.Error("truncated = false, want true")
+	}
+	if len(data) != max {
+		t.Errorf("got %d bytes, want %d", len(data), max)
+	}
+	// stops reading once the cap is hit instead of draining the file
+	if f.reads != 2 {
+		t.Errorf("reads = %d, want 2", f.reads)
+	}
+	if f.closes != 1 {
+		t.Errorf("closes = %d, want 1", f.closes)
+	}
+}
+
+func TestSFTPReadFilesStatus(t *testing.T) {
+	f := &fakeSFTP{files: map[string][]byte{
+		"/proc/uptime": []byte("12.5 40.1\n"),
+		"/proc/empty":  nil,
+	}}
+	sc := newFakeSFTP(t, f)
+
+	outs, err := sc.readFiles([]string{"/proc/uptime", "/proc/missing", "/proc/empty"}, 1<<20)
+	if err != nil {
+		t.Fatalf("readFiles: %v", err)
+	}
+	want := []Output{
+		{Stdout: "12.5 40.1\n"},
+		{Stderr: "/proc/missing: No such file\n", ExitCode: 1},
+		{}, // EOF on the first read
+	}
+	if len(outs) != len(want) {
+		t.Fatalf("got %d outputs, want %d", len(outs), len(want))
+	}
+	for i := range want {
+		if outs[i] != want[i] {
+			t.Errorf("outs[%d] = %+v, want %+v", i, outs[i], want[i])
+		}
+	}
+}
+
+func TestSFTPCallStatus(t *testing.T) {
+	sc := newFakeSFTP(t, &fakeSFTP{})
+
+	_, err := sc.call(sftpOpen, appendString(nil, "/nope"), sftpHandle)
+	var se *sftpStatusError
+	if !errors.As(err, &se) {
+		t.Fatalf("err = %v, want *sftpStatusError", err)
+	}
+	if se.code != 2 || se.msg != "No such file" {
+		t.Errorf("status = %d %q, want 2 %q", se.code, se.msg, "No such file")
+	}
+}
+
+func TestSFTPCallIDMismatch(t *testing.T) {
+	f := &fakeSFTP{files: map[string][]byte{"/proc/uptime": []byte("1 2\n")}, badID: true}
+	sc := newFakeSFTP(t, f)
+
+	_, _, err := sc.readFile("/proc/uptime", 1<<20)
+	if err == nil || !strings.Contains(err.Error(), "reply id mismatch") {
+		t.Fatalf("err = %v, want reply id mismatch", err)
+	}
+	var se *sftpStatusError
+	if errors.As(err, &se) {
+		t.Errorf("id mismatch reported as status error %v", se)
+	}
+}
+
+// unhex decodes a hex dump; spaces and newlines are for readability.
+func unhex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(strings.Join(strings.Fields(s), ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// TestSFTPWireFormat checks the exact request bytes against the draft and
+// feeds hand-written replies, so an encoding bug cannot cancel itself out
+// the way it could against a server built from the same helpers.
+func TestSFTPWireFormat(t *testing.T) {
+	steps := []struct {
+		name       string
+		req, reply string
+	}{
+		{
+			"INIT v3 / VERSION 3",
+			"00000005 01 00000003",
+			"00000005 02 00000003",
+		},
+		{
+			"OPEN /proc/uptime READ / HANDLE h1",
+			"0000001d 03 00000001 0000000c 2f70726f632f757074696d65 00000001 00000000",
+			"0000000b 66 00000001 00000002 6831",
+		},
+		{
+			"READ h1 @0 32K / DATA",
+			"00000017 05 00000002 00000002 6831 0000000000000000 00008000",
+			"00000013 67 00000002 0000000a 31322e352034302e310a", // "12.5 40.1\n"
+		},
+		{
+			"READ h1 @10 32K / STATUS EOF",
+			"00000017 05 00000003 00000002 6831 000000000000000a 00008000",
+			"00000014 65 00000003 00000001 00000003 454f46 00000000",
+		},
+		{
+			"CLOSE h1 / STATUS OK",
+			"0000000b 04 00000004 00000002 6831",
+			"00000011 65 00000004 00000000 00000000 00000000",
+		},
+		{
+			"OPEN /x / STATUS no such file",
+			"00000013 03 00000005 00000002 2f78 00000001 00000000",
+			"0000001d 65 00000005 00000002 0000000c 4e6f20737563682066696c65 00000000",
+		},
+	}
+
+	cr, sw := io.Pipe() // server -> client
+	sr, cw := io.Pipe() // client -> server
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer sw.Close()
+		for _, st := range steps {
+			want := unhex(t, st.req)
+			got := make([]byte, len(want))
+			if _, err := io.ReadFull(sr, got); err != nil {
+				t.Errorf("%s: read request: %v", st.name, err)
+				return
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("%s: request\n got % x\nwant % x", st.name, got, want)
+				return
+			}
+			if _, err := sw.Write(unhex(t, st.reply)); err != nil {
+				t.Errorf("%s: write reply: %v", st.name, err)
+				return
+			}
+		}
+	}()
+	defer func() {
+		_ = cw.Close()
+		<-done
+	}()
+
+	sc := &sftpConn{w: cw, r: bufio.NewReader(cr)}
+	if err := sc.init(); err != nil {
+		t.Fatalf("init: %v", err)
+	}
+	outs, err := sc.readFiles([]string{"/proc/uptime", "/x"}, 1<<20)
+	if err != nil {
+		t.Fatalf("readFiles: %v", err)
+	}
+	want := []Output{
+		{Stdout: "12.5 40.1\n"},
+		{Stderr: "/x: No such file\n", ExitCode: 1},
+	}
+	if len(outs) != len(want) || outs[0] != want[0] || outs[1] != want[1] {
+		t.Errorf("outs = %+v, want %+v", outs, want)
+	}
+}
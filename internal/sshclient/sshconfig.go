@@ -0,0 +1,435 @@
+package sshclient
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ConfigFile is a parsed OpenSSH client configuration file (ssh_config(5)).
+// Only the options the exporter uses are resolved, see HostConfig.
+type ConfigFile struct {
+	blocks []sshConfigBlock
+}
+
+// HostConfig is what a ConfigFile says about one host alias.
+// Zero values mean "not set in the file".
+type HostConfig struct {
+	HostName            string
+	Port                int
+	User                string
+	IdentityFiles       []string
+	ProxyJump           string // "" or "none" when unset/disabled
+	UserKnownHostsFiles []string
+}
+
+// sshConfigBlock is the global section (no Host/Match yet), a Host block or a Match block.
+type sshConfigBlock struct {
+	host     []string         // Host patterns
+	match    []matchCriterion // Match criteria (ANDed)
+	isMatch  bool
+	isGlobal bool
+	opts     []sshConfigOption
+}
+
+type sshConfigOption struct {
+	key  string // lower-case keyword
+	args []string
+}
+
+type matchCriterion struct {
+	negate bool
+	kind   string // lower-case: all, host, originalhost, user, localuser, final, ...
+	arg    string
+}
+
+// ParseConfigFile reads an OpenSSH client config. Include directives are
+// expanded in place (relative paths are relative to the including file).
+func ParseConfigFile(path string) (*ConfigFile, error) {
+	c := &ConfigFile{blocks: []sshConfigBlock{{isGlobal: true}}}
+	if err := c.parseFile(path, 0); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *ConfigFile) parseFile(path string, depth int) error {
+	if depth > 16 {
+		return fmt.Errorf("ssh_config %s: Include nested too deep", path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("read ssh_config: %w", err)
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	line := 0
+	for sc.Scan() {
+		line++
+		key, args, err := splitConfigLine(sc.Text())
+		if err != nil {
+			return fmt.Errorf("ssh_config %s:%d: %w", path, line, err)
+		}
+		if key == "" {
+			continue
+		}
+
+		switch key {
+		case "host":
+			if len(args) == 0 {
+				return fmt.Errorf("ssh_config %s:%d: Host without patterns", path, line)
+			}
+			c.blocks = append(c.blocks, sshConfigBlock{host: args})
+
+		case "match":
+			crit, err := parseMatch(args)
+			if err != nil {
+				return fmt.Errorf("ssh_config %s:%d: %w", path, line, err)
+			}
+			c.blocks = append(c.blocks, sshConfigBlock{isMatch: true, match: crit})
+
+		case "include":
+			for _, pat := range args {
+				pat = expandTilde(pat)
+				if !filepath.IsAbs(pat) {
+					pat = filepath.Join(filepath.Dir(path), pat)
+				}
+				matches, err := filepath.Glob(pat)
+				if err != nil {
+					return fmt.Errorf("ssh_config %s:%d: %w", path, line, err)
+				}
+				for _, m := range matches {
+					if err := c.parseFile(m, depth+1); err != nil {
+						return err
+					}
+				}
+			}
+
+		default:
+			b := &c.blocks[len(c.blocks)-1]
+			b.opts = append(b.opts, sshConfigOption{key: key, args: args})
+		}
+	}
+	return sc.Err()
+}
+
+// splitConfigLine returns the lower-cased keyword and its arguments.
+// Accepts "Key value", "Key=value" and double-quoted arguments.
+func splitConfigLine(ln string) (string, []string, error) {
+	ln = strings.TrimSpace(ln)
+	if ln == "" || strings.HasPrefix(ln, "#") {
+		return "", nil, nil
+	}
+
+	i := strings.IndexAny(ln, " \t=")
+	if i < 0 {
+		return strings.ToLower(ln), nil, nil
+	}
+	key := strings.ToLower(ln[:i])
+	rest := strings.TrimLeft(ln[i:], " \t")
+	if strings.HasPrefix(rest, "=") {
+		rest = strings.TrimLeft(rest[1:], " \t")
+	}
+
+	var (
+		args   []string
+		cur    strings.Builder
+		quoted bool
+		inArg  bool
+	)
+	for _, r := range rest {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			inArg = true
+		case (r == ' ' || r == '\t') && !quoted:
+			if inArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				inArg = false
+			}
+		default:
+			cur.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quoted {
+		return "", nil, fmt.Errorf("unterminated quote")
+	}
+	if inArg {
+		args = append(args, cur.String())
+	}
+	return key, args, nil
+}
+
+func parseMatch(args []string) ([]matchCriterion, error) {
+	var out []matchCriterion
+	for i := 0; i < len(args); i++ {
+		kind := strings.ToLower(args[i])
+		negate := strings.HasPrefix(kind, "!")
+		kind = strings.TrimPrefix(kind, "!")
+
+		switch kind {
+		case "all", "canonical", "final":
+			out = append(out, matchCriterion{negate: negate, kind: kind})
+		case "host", "originalhost", "user", "localuser", "exec", "localnetwork", "tagged":
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("Match %s needs an argument", kind)
+			}
+			i++
+			out = append(out, matchCriterion{negate: negate, kind: kind, arg: args[i]})
+		default:
+			return nil, fmt.Errorf("unsupported Match criterion %q", args[i])
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("Match without criteria")
+	}
+	return out, nil
+}
+
+// resolveState carries values obtained so far while walking the file.
+type resolveState struct {
+	alias string // host as given (Host / Match originalhost)
+	user  string // user given by the caller, wins over User options
+
+	hc   HostConfig
+	seen map[string]bool // first obtained value wins
+}
+
+// Resolve walks the file top to bottom the way OpenSSH does: every matching
+// Host/Match block contributes, and the first value obtained for an option
+// wins (IdentityFile accumulates). user is the login user if already known
+// (used by "Match user"), otherwise "".
+func (c *ConfigFile) Resolve(alias, login string) HostConfig {
+	st := &resolveState{alias: alias, user: login, seen: make(map[string]bool)}
+
+	for _, b := range c.blocks {
+		if !b.matches(st) {
+			continue
+		}
+		for _, o := range b.opts {
+			st.apply(o)
+		}
+	}
+
+	hc := st.hc
+	hostname := hc.HostName
+	if hostname == "" {
+		hostname = alias
+	}
+	port := hc.Port
+	if port == 0 {
+		port = 22
+	}
+	tokens := map[byte]string{
+		'h': hostname,
+		'n': alias,
+		'r': firstNonEmpty(login, hc.User),
+		'p': strconv.Itoa(port),
+		'u': localUserName(),
+		'd': homeDir(),
+	}
+	for i, f := range hc.IdentityFiles {
+		hc.IdentityFiles[i] = expandTilde(expandTokens(f, tokens))
+	}
+	for i, f := range hc.UserKnownHostsFiles {
+		hc.UserKnownHostsFiles[i] = expandTilde(expandTokens(f, tokens))
+	}
+	return hc
+}
+
+func (b sshConfigBlock) matches(st *resolveState) bool {
+	switch {
+	case b.isGlobal:
+		return true
+	case !b.isMatch:
+		return matchPatternList(strings.ToLower(st.alias), b.host)
+	}
+
+	for _, m := range b.match {
+		var ok bool
+		switch m.kind {
+		case "all", "final":
+			// single resolution pass: it is always the final one
+			ok = true
+		case "host":
+			h := st.hc.HostName
+			if h == "" {
+				h = st.alias
+			}
+			ok = matchPatternList(strings.ToLower(h), strings.Split(m.arg, ","))
+		case "originalhost":
+			ok = matchPatternList(strings.ToLower(st.alias), strings.Split(m.arg, ","))
+		case "user":
+			u := st.user
+			if u == "" {
+				u = st.hc.User
+			}
+			ok = matchPatternList(u, strings.Split(m.arg, ","))
+		case "localuser":
+			ok = matchPatternList(localUserName(), strings.Split(m.arg, ","))
+		default:
+			// canonical, exec (never run commands), localnetwork, tagged
+			ok = false
+		}
+		if m.negate {
+			ok = !ok
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (st *resolveState) apply(o sshConfigOption) {
+	if len(o.args) == 0 {
+		return
+	}
+
+	// accumulates
+	if o.key == "identityfile" {
+		st.hc.IdentityFiles = append(st.hc.IdentityFiles, o.args[0])
+		return
+	}
+
+	if st.seen[o.key] {
+		return
+	}
+
+	switch o.key {
+	case "hostname":
+		st.hc.HostName = expandTokens(o.args[0], map[byte]string{'h': st.alias})
+	case "port":
+		n, err := strconv.Atoi(o.args[0])
+		if err != nil || n < 1 || n > 65535 {
+			return
+		}
+		st.hc.Port = n
+	case "user":
+		st.hc.User = o.args[0]
+	case "proxyjump":
+		st.hc.ProxyJump = o.args[0]
+	case "userknownhostsfile":
+		st.hc.UserKnownHostsFiles = append([]string(nil), o.args...)
+	default:
+		return
+	}
+	st.seen[o.key] = true
+}
+
+// matchPatternList follows ssh_config PATTERNS: any matching negated pattern
+// rejects, otherwise one matching positive pattern accepts.
+func matchPatternList(s string, patterns []string) bool {
+	matched := false
+	for _, arg := range patterns {
+		for _, p := range strings.Split(arg, ",") {
+			if p == "" {
+				continue
+			}
+			neg := strings.HasPrefix(p, "!")
+			p = strings.ToLower(strings.TrimPrefix(p, "!"))
+			if !wildcardMatch(p, strings.ToLower(s)) {
+				continue
+			}
+			if neg {
+				return false
+			}
+			matched = true
+		}
+	}
+	return matched
+}
+
+// wildcardMatch supports '*' and '?' like OpenSSH match_pattern.
+func wildcardMatch(pat, s string) bool {
+	for len(pat) > 0 {
+		switch pat[0] {
+		case '*':
+			for len(pat) > 0 && pat[0] == '*' {
+				pat = pat[1:]
+			}
+			if pat == "" {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if wildcardMatch(pat, s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if s == "" {
+				return false
+			}
+		default:
+			if s == "" || s[0] != pat[0] {
+				return false
+			}
+		}
+		pat, s = pat[1:], s[1:]
+	}
+	return s == ""
+}
+
+func expandTokens(v string, tokens map[byte]string) string {
+	if !strings.Contains(v, "%") {
+		return v
+	}
+	var b strings.Builder
+	for i := 0; i < len(v); i++ {
+		if v[i] != '%' || i+1 >= len(v) {
+			b.WriteByte(v[i])
+			continue
+		}
+		i++
+		if v[i] == '%' {
+			b.WriteByte('%')
+			continue
+		}
+		if t, ok := tokens[v[i]]; ok {
+			b.WriteString(t)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(v[i])
+	}
+	return b.String()
+}
+
+func expandTilde(p string) string {
+	if p == "~" {
+		return homeDir()
+	}
+	if strings.HasPrefix(p, "~/") {
+		return filepath.Join(homeDir(), p[2:])
+	}
+	return p
+}
+
+func homeDir() string {
+	h, _ := os.UserHomeDir()
+	return h
+}
+
+func localUserName() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+func firstNonEmpty(vs ...string) string {
+	for _, v := range vs {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,227 @@
+package sshclient
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeConfig writes files (name -> content) into a temp dir and parses the
+// first one, "config".
+func writeConfig(t *testing.T, files map[string]string) *ConfigFile {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c, err := ParseConfigFile(filepath.Join(dir, "config"))
+	if err != nil {
+		t.Fatalf("ParseConfigFile: %v", err)
+	}
+	return c
+}
+
+func TestResolve(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	tests := []struct {
+		name  string
+		files map[string]string
+		alias string
+		login string
+		want  HostConfig
+	}{
+		{
+			name: "first value wins",
+			files: map[string]string{"config": `
+Host web*
+    HostName web.example.net
+Host *
+    HostName ignored.example.net
+    Port 2222
+    User admin
+Host web1
+    Port 22
+    User ignored
+`},
+			alias: "web1",
+			want:  HostConfig{HostName: "web.example.net", Port: 2222, User: "admin"},
+		},
+		{
+			name: "global section before any Host",
+			files: map[string]string{"config": `
+User everyone
+Host web1
+    User ignored
+`},
+			alias: "web1",
+			want:  HostConfig{User: "everyone"},
+		},
+		{
+			name: "IdentityFile accumulates",
+			files: map[string]string{"config": `
+Host web1
+    IdentityFile /keys/web1
+Host *
+    IdentityFile /keys/default
+    IdentityFile /keys/fallback
+`},
+			alias: "web1",
+			want:  HostConfig{IdentityFiles: []string{"/keys/web1", "/keys/default", "/keys/fallback"}},
+		},
+		{
+			name: "Match host sees an earlier HostName",
+			files: map[string]string{"config": `
+Host db
+    HostName db1.internal
+Match host *.internal
+    User dbuser
+Match originalhost db
+    Port 2200
+Match host db
+    ProxyJump never
+`},
+			alias: "db",
+			want:  HostConfig{HostName: "db1.internal", Port: 2200, User: "dbuser"},
+		},
+		{
+			name: "negated pattern excludes",
+			files: map[string]string{"config": `
+Host * !bastion
+    ProxyJump bastion
+Host bastion
+    HostName bastion.example.net
+`},
+			alias: "bastion",
+			want:  HostConfig{HostName: "bastion.example.net"},
+		},
+		{
+			name: "negated pattern passes others",
+			files: map[string]string{"config": `
+Host * !bastion
+    ProxyJump bastion
+`},
+			alias: "web1",
+			want:  HostConfig{ProxyJump: "bastion"},
+		},
+		{
+			name: "negated Match criterion",
+			files: map[string]string{"config": `
+Match !host web*
+    User notweb
+Match all
+    User fallback
+`},
+			alias: "web1",
+			want:  HostConfig{User: "fallback"},
+		},
+		{
+			name: "Match user uses the login",
+			files: map[string]string{"config": `
+Match user monitor
+    IdentityFile /keys/monitor
+`},
+			alias: "web1",
+			login: "monitor",
+			want:  HostConfig{IdentityFiles: []string{"/keys/monitor"}},
+		},
+		{
+			name: "Match exec never matches",
+			files: map[string]string{"config": `
+Match exec "true"
+    User executed
+`},
+			alias: "web1",
+			want:  HostConfig{},
+		},
+		{
+			name: "token and tilde expansion",
+			files: map[string]string{"config": `
+Host web1
+    HostName %h.example.net
+    User monitor
+    Port 2222
+    IdentityFile ~/.ssh/%h_%r_%p
+    UserKnownHostsFile ~/known/%n %d/other
+`},
+			alias: "web1",
+			want: HostConfig{
+				HostName:            "web1.example.net",
+				Port:                2222,
+				User:                "monitor",
+				IdentityFiles:       []string{filepath.Join(home, ".ssh", "web1.example.net_monitor_2222")},
+				UserKnownHostsFiles: []string{filepath.Join(home, "known", "web1"), home + "/other"},
+			},
+		},
+		{
+			name: "Include in place, relative to the including file",
+			files: map[string]string{
+				"config": `
+Include conf.d/*.conf
+Host web1
+    HostName late.example.net
+    User late
+`,
+				"conf.d/10-web.conf": `
+Host web1
+    HostName included.example.net
+`,
+				"conf.d/20-all.conf": `
+Host *
+    Port 2200
+`,
+			},
+			alias: "web1",
+			want:  HostConfig{HostName: "included.example.net", Port: 2200, User: "late"},
+		},
+		{
+			name: "Key=value and quoted arguments",
+			files: map[string]string{"config": `
+Host=web1
+    HostName="web one.example.net"
+    Port=2022
+`},
+			alias: "web1",
+			want:  HostConfig{HostName: "web one.example.net", Port: 2022},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := writeConfig(t, tt.files)
+			got := c.Resolve(tt.alias, tt.login)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Resolve(%q, %q)\n got %+v\nwant %+v", tt.alias, tt.login, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseConfigFileErrors(t *testing.T) {
+	for name, content := range map[string]string{
+		"host without patterns": "Host\n",
+		"unterminated quote":    "Host web1\n    HostName \"web\n",
+		"unknown Match":         "Match nonsense x\n",
+		"Match without arg":     "Match host\n",
+		"include loop":          "Include config\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			p := filepath.Join(dir, "config")
+			if err := os.WriteFile(p, []byte(content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := ParseConfigFile(p); err == nil {
+				t.Errorf("ParseConfigFile(%q): no error", content)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package sshclient
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// Endpoint is one SSH hop.
+type Endpoint struct {
+	Host string
+	Port int // 0 = Config.Port
+	User string
+	Auth Auth
+
+	// overrides Config.KnownHostsFiles/TOFU for this hop (ssh_config UserKnownHostsFile)
+	KnownHostsFiles []string
+
+	// non-empty lists override Config.Algorithms for this hop
+	Algorithms Algorithms
+}
+
+// Transports: how commands reach a target.
+const (
+	TransportExec = "exec" // shell command per session (default)
+	TransportSFTP = "sftp" // SFTP subsystem only; file-backed commands are read directly
+)
+
+// Target is the host to run commands on, reached through Jump in order
+// (Jump[0] is dialed directly, each next hop through the previous one).
+type Target struct {
+	Endpoint
+	Jump []Endpoint
+
+	Transport string // "" = TransportExec
+}
+
+func (c *Client) addr(e Endpoint) string {
+	port := e.Port
+	if port <= 0 {
+		port = c.cfg.Port
+	}
+	return net.JoinHostPort(e.Host, fmt.Sprintf("%d", port))
+}
+
+// hostID names e as reached through route (the hops before it), in
+// known_hosts normal form: "10.0.0.5" direct, "bastion/10.0.0.5" behind a
+// bastion. Host key state and negotiated crypto are kept per hostID so equal
+// private addresses at different sites stay apart.
+func (c *Client) hostID(route []Endpoint, e Endpoint) string {
+	parts := make([]string, 0, len(route)+1)
+	for _, h := range route {
+		parts = append(parts, knownhosts.Normalize(c.addr(h)))
+	}
+	parts = append(parts, knownhosts.Normalize(c.addr(e)))
+	return strings.Join(parts, "/")
+}
+
+// chainKey identifies the connection to the last hop of chain, including
+// the route to it, so connections are only shared when they are equivalent.
+func (c *Client) chainKey(chain []Endpoint) string {
+	parts := make([]string, 0, len(chain))
+	for _, e := range chain {
+		parts = append(parts, e.User+"@"+c.addr(e)+"/"+e.Auth.key()+e.Algorithms.key())
+	}
+	return strings.Join(parts, " -> ")
+}
+
+func (a Algorithms) key() string {
+	if len(a.KeyExchanges)+len(a.Ciphers)+len(a.MACs)+len(a.HostKeys) == 0 {
+		return ""
+	}
+	return "/" + strings.Join(a.KeyExchanges, ",") + ";" + strings.Join(a.Ciphers, ",") +
+		";" + strings.Join(a.MACs, ",") + ";" + strings.Join(a.HostKeys, ",")
+}
@@ -0,0 +1,125 @@
+package sshclient
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// tofuStore pins the first host key seen per host (trust on first use).
+// State is kept in a known_hosts formatted file so it can be inspected or
+// edited with the usual tools; delete a line to accept a new key (the file
+// is re-read on a mismatch, no restart needed).
+type tofuStore struct {
+	mu    sync.Mutex
+	path  string
+	lines int
+	keys  map[string]knownhosts.KnownKey // key: Client.hostID, e.g. "bastion/10.0.0.5"
+}
+
+func loadTOFUStore(path string) (*tofuStore, error) {
+	s := &tofuStore{path: path}
+	if err := s.reloadLocked(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// reloadLocked (re)reads the state file, so edits made while the exporter
+// runs (a deleted line) take effect.
+func (s *tofuStore) reloadLocked() error {
+	keys := make(map[string]knownhosts.KnownKey)
+
+	b, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		s.keys, s.lines = keys, 0
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read tofu state: %w", err)
+	}
+
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	line := 0
+	for sc.Scan() {
+		line++
+		ln := bytes.TrimSpace(sc.Bytes())
+		if len(ln) == 0 || ln[0] == '#' {
+			continue
+		}
+		_, hosts, key, _, _, err := ssh.ParseKnownHosts(ln)
+		if err != nil {
+			return fmt.Errorf("tofu state %s:%d: %w", s.path, line, err)
+		}
+		for _, h := range hosts {
+			h = knownhosts.Normalize(h)
+			if _, ok := keys[h]; !ok {
+				keys[h] = knownhosts.KnownKey{Key: key, Filename: s.path, Line: line}
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	s.keys, s.lines = keys, line
+	return nil
+}
+
+// Check implements ssh.HostKeyCallback. Unknown hosts are pinned,
+// known hosts must present the pinned key (same errors as knownhosts).
+func (s *tofuStore) Check(hostname string, _ net.Addr, key ssh.PublicKey) error {
+	h := knownhosts.Normalize(hostname)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if kk, ok := s.keys[h]; ok {
+		if bytes.Equal(kk.Key.Marshal(), key.Marshal()) {
+			return nil
+		}
+		// the operator may have removed the line to accept a new key
+		if err := s.reloadLocked(); err != nil {
+			return err
+		}
+		if kk, ok := s.keys[h]; ok {
+			if bytes.Equal(kk.Key.Marshal(), key.Marshal()) {
+				return nil
+			}
+			return &knownhosts.KeyError{Want: []knownhosts.KnownKey{kk}}
+		}
+	}
+
+	if err := s.appendLocked(h, key); err != nil {
+		return fmt.Errorf("pin host key for %s: %w", hostname, err)
+	}
+	s.keys[h] = knownhosts.KnownKey{Key: key, Filename: s.path, Line: s.lines}
+	return nil
+}
+
+// Lookup returns the pinned key for hostname, if any.
+func (s *tofuStore) Lookup(hostname string) (ssh.PublicKey, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kk, ok := s.keys[knownhosts.Normalize(hostname)]
+	return kk.Key, ok
+}
+
+func (s *tofuStore) appendLocked(host string, key ssh.PublicKey) error {
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := fmt.Fprintln(f, knownhosts.Line([]string{host}, key)); err != nil {
+		return err
+	}
+	s.lines++
+	return nil
+}
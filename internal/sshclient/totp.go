@@ -0,0 +1,51 @@
+package sshclient
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TOTP parameters: the RFC 6238 defaults every authenticator app uses.
+const (
+	totpStep   = 30 * time.Second
+	totpDigits = 6
+)
+
+// DecodeTOTPSeed decodes a base32 TOTP seed as shown by authenticator setup
+// (case, spaces and missing padding are tolerated).
+func DecodeTOTPSeed(s string) ([]byte, error) {
+	s = strings.ToUpper(strings.Join(strings.Fields(s), ""))
+	s = strings.TrimRight(s, "=")
+	seed, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("decode totp seed: %w", err)
+	}
+	if len(seed) == 0 {
+		return nil, fmt.Errorf("decode totp seed: empty")
+	}
+	return seed, nil
+}
+
+// TOTP returns the RFC 6238 code (HMAC-SHA1, 30s step, 6 digits) for seed at t.
+func TOTP(seed []byte, t time.Time) string {
+	counter := uint64(t.Unix()) / uint64(totpStep/time.Second)
+
+	mac := hmac.New(sha1.New, seed)
+	_ = binary.Write(mac, binary.BigEndian, counter)
+	sum := mac.Sum(nil)
+
+	// dynamic truncation (RFC 4226 section 5.3)
+	off := sum[len(sum)-1] & 0x0f
+	code := binary.BigEndian.Uint32(sum[off:off+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < totpDigits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", totpDigits, code%mod)
+}
@@ -0,0 +1,143 @@
+package sshclient
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// RFC 6238 appendix B, SHA-1 seed; the RFC prints 8 digits, we send the
+// last 6.
+var rfc6238Seed = []byte("12345678901234567890")
+
+func TestTOTPRFC6238(t *testing.T) {
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		if got := TOTP(rfc6238Seed, time.Unix(tt.unix, 0)); got != tt.want {
+			t.Errorf("TOTP(t=%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeTOTPSeed(t *testing.T) {
+	for _, in := range []string{
+		"GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+		"gezd gnbv gy3t qojq gezd gnbv gy3t qojq",
+		"GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ====",
+	} {
+		seed, err := DecodeTOTPSeed(in)
+		if err != nil {
+			t.Errorf("DecodeTOTPSeed(%q): %v", in, err)
+			continue
+		}
+		if !bytes.Equal(seed, rfc6238Seed) {
+			t.Errorf("DecodeTOTPSeed(%q) = %q", in, seed)
+		}
+	}
+	for _, in := range []string{"", "   ", "not base32!"} {
+		if _, err := DecodeTOTPSeed(in); err == nil {
+			t.Errorf("DecodeTOTPSeed(%q): no error", in)
+		}
+	}
+}
+
+// answer runs a's keyboard-interactive challenge on questions.
+func answer(t *testing.T, a Auth, questions ...string) ([]string, error) {
+	t.Helper()
+	m, err := a.keyboardInteractive()
+	if err != nil {
+		t.Fatalf("keyboardInteractive: %v", err)
+	}
+	challenge, ok := m.(ssh.KeyboardInteractiveChallenge)
+	if !ok {
+		t.Fatalf("keyboardInteractive returned %T", m)
+	}
+	return challenge("monitor", "", questions, make([]bool, len(questions)))
+}
+
+func TestKeyboardInteractiveRouting(t *testing.T) {
+	const pass = "s3cret"
+	isCode := func(s string) bool {
+		// the step may roll over between the call and this check
+		now := time.Now()
+		return s == TOTP(rfc6238Seed, now) || s == TOTP(rfc6238Seed, now.Add(-totpStep))
+	}
+
+	t.Run("password only answers every prompt", func(t *testing.T) {
+		got, err := answer(t, Auth{Password: pass}, "Password: ", "Enter PIN: ")
+		if err != nil || len(got) != 2 || got[0] != pass || got[1] != pass {
+			t.Errorf("answers = %q, %v", got, err)
+		}
+	})
+
+	t.Run("totp checked before password", func(t *testing.T) {
+		got, err := answer(t, Auth{Password: pass, TOTPSeed: rfc6238Seed},
+			"Password: ", "Verification code: ", "One-time password: ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got[0] != pass || !isCode(got[1]) || !isCode(got[2]) {
+			t.Errorf("answers = %q", got)
+		}
+	})
+
+	t.Run("totp only", func(t *testing.T) {
+		got, err := answer(t, Auth{TOTPSeed: rfc6238Seed}, "Authenticator token: ")
+		if err != nil || !isCode(got[0]) {
+			t.Errorf("answers = %q, %v", got, err)
+		}
+	})
+
+	t.Run("custom rules", func(t *testing.T) {
+		a := Auth{Password: pass, TOTPSeed: rfc6238Seed, PasswordPrompt: `^Kennwort`, TOTPPrompt: `^Einmalcode`}
+		got, err := answer(t, a, "Kennwort: ", "Einmalcode: ")
+		if err != nil || got[0] != pass || !isCode(got[1]) {
+			t.Errorf("answers = %q, %v", got, err)
+		}
+	})
+
+	t.Run("no questions", func(t *testing.T) {
+		got, err := answer(t, Auth{Password: pass, TOTPSeed: rfc6238Seed})
+		if err != nil || len(got) != 0 {
+			t.Errorf("answers = %q, %v", got, err)
+		}
+	})
+
+	unanswered := []struct {
+		name     string
+		auth     Auth
+		question string
+	}{
+		{"unmatched with totp", Auth{Password: pass, TOTPSeed: rfc6238Seed}, "Security question: "},
+		{"unmatched with custom rule", Auth{Password: pass, PasswordPrompt: `^Password`}, "Enter PIN: "},
+		{"totp prompt without seed", Auth{Password: pass, PasswordPrompt: `^Password`}, "Verification code: "},
+		{"password prompt without password", Auth{TOTPSeed: rfc6238Seed}, "Password: "},
+	}
+	for _, tt := range unanswered {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := answer(t, tt.auth, tt.question)
+			if err == nil || !strings.Contains(err.Error(), "no answer") {
+				t.Errorf("answers = %q, err = %v; want no answer error", got, err)
+			}
+		})
+	}
+
+	t.Run("bad rule", func(t *testing.T) {
+		if _, err := (Auth{Password: pass, TOTPPrompt: "("}).keyboardInteractive(); err == nil {
+			t.Error("invalid totp_prompt accepted")
+		}
+	})
+}
@@ -0,0 +1,62 @@
+package sshclient
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Exec phases, in order.
+const (
+	PhaseConnect = "connect" // TCP connect (or direct-tcpip channel through a bastion)
+	PhaseKex     = "kex"     // version exchange + key exchange + host key check
+	PhaseAuth    = "auth"    // user authentication
+	PhaseSession = "session" // session channel open
+	PhaseCommand = "command" // remote command runtime
+)
+
+// Phases lists every phase a Trace may report.
+var Phases = []string{PhaseConnect, PhaseKex, PhaseAuth, PhaseSession, PhaseCommand}
+
+// Trace collects per-phase timings of one Exec. Phases that did not happen
+// (e.g. connect/kex/auth on a pooled connection) stay zero; hops through
+// bastions add to the same phases.
+type Trace struct {
+	mu     sync.Mutex
+	phases map[string]time.Duration
+}
+
+type traceKey struct{}
+
+// WithTrace returns a ctx that makes Exec record its timings into t.
+func WithTrace(ctx context.Context, t *Trace) context.Context {
+	return context.WithValue(ctx, traceKey{}, t)
+}
+
+func traceFrom(ctx context.Context) *Trace {
+	t, _ := ctx.Value(traceKey{}).(*Trace)
+	return t
+}
+
+func (t *Trace) add(phase string, d time.Duration) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.phases == nil {
+		t.phases = make(map[string]time.Duration)
+	}
+	t.phases[phase] += d
+}
+
+// Seconds returns every phase in Phases with its duration in seconds.
+func (t *Trace) Seconds() map[string]float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make(map[string]float64, len(Phases))
+	for _, p := range Phases {
+		out[p] = t.phases[p].Seconds()
+	}
+	return out
+}